@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+// schemaVersion is stored in PRAGMA user_version once initializeDB has
+// migrated a database. Bump it with every migration so the database is
+// backed up before the migration runs.
+const schemaVersion = 5
+
+// defaultBackups is how many backups are kept when the config doesn't say.
+const defaultBackups = 10
+
+// backupInterval is how old the newest backup can get before opening the
+// database takes a new one.
+const backupInterval = 24 * time.Hour
+
+// backupTimeFormat starts every backup name.
+const backupTimeFormat = "20060102-150405"
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "List, create and restore backups of the workspace database",
+	Long: `chkmrk backs up a database before migrating it and when the newest
+backup is a day old. Backups live in <database>.backups/ and the newest
+"backups" (from the config, default 10) are kept.`,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backups, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupList,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Back up the database now",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupCreate,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Replace the database with a backup, by number or name",
+	Long: `Replace the database with a backup from backup list, given by its number
+or name. The database is backed up first, so a restore can be undone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackupRestore,
+}
+
+func init() {
+	backupCmd.AddCommand(backupListCmd, backupCreateCmd, backupRestoreCmd)
+}
+
+// backup is one backup file. Its name is the time it was taken and why.
+type backup struct {
+	Name string
+	Path string
+	Time time.Time
+	Size int64
+
+	modified time.Time
+}
+
+func backupDir(path string) string {
+	return path + ".backups"
+}
+
+// listBackups returns the backups of the database at path, newest first.
+func listBackups(path string) ([]backup, error) {
+	files, err := filepath.Glob(filepath.Join(backupDir(path), "*.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".db")
+		if len(name) < len(backupTimeFormat) {
+			continue
+		}
+		taken, err := time.ParseInLocation(backupTimeFormat, name[:len(backupTimeFormat)], time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{Name: name, Path: file, Time: taken, Size: fi.Size(), modified: fi.ModTime()})
+	}
+	// Names only have whole seconds, so order by when the file was written.
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modified.After(backups[j].modified) })
+	return backups, nil
+}
+
+// copyDB copies the main database of src over dst with the SQLite online
+// backup API, which gives a consistent copy while other connections keep
+// using src.
+func copyDB(dst, src *sql.DB) error {
+	ctx := context.Background()
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(d interface{}) error {
+		return srcConn.Raw(func(s interface{}) error {
+			dc, ok := d.(*sqlite3.SQLiteConn)
+			sc, ok2 := s.(*sqlite3.SQLiteConn)
+			if !ok || !ok2 {
+				return errors.New("backups need direct access to the database; stop chkmrk daemon first")
+			}
+			b, err := dc.Backup("main", sc, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+}
+
+// createBackup copies db, the database at path, into a new backup and
+// drops the oldest backups beyond keep.
+func createBackup(db *sql.DB, path, reason string, keep int) (backup, error) {
+	dir := backupDir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return backup{}, err
+	}
+
+	name := time.Now().Format(backupTimeFormat) + "-" + reason
+	file := filepath.Join(dir, name+".db")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			break
+		}
+		file = filepath.Join(dir, fmt.Sprintf("%s-%d.db", name, n))
+	}
+
+	dst, err := sql.Open("sqlite3", file)
+	if err != nil {
+		return backup{}, err
+	}
+	err = copyDB(dst, db)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file)
+		return backup{}, err
+	}
+
+	if keep <= 0 {
+		keep = defaultBackups
+	}
+	backups, err := listBackups(path)
+	if err != nil {
+		return backup{}, err
+	}
+	for _, old := range backups[min(keep, len(backups)):] {
+		os.Remove(old.Path)
+	}
+	for _, b := range backups {
+		if b.Path == file {
+			return b, nil
+		}
+	}
+	return backup{Name: strings.TrimSuffix(filepath.Base(file), ".db"), Path: file}, nil
+}
+
+// autoBackup backs up an existing database at path before initializeDB
+// migrates it, or when the newest backup is older than backupInterval.
+func autoBackup(db *sql.DB, path string, keep int) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil {
+		return err
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	if version < schemaVersion {
+		_, err := createBackup(db, path, "migration", keep)
+		return err
+	}
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 || time.Since(backups[0].Time) > backupInterval {
+		if _, err := createBackup(db, path, "daily", keep); err != nil {
+			log.Printf("Daily backup of %s failed: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// restoreBackup replaces db, the database at path, with the backup named
+// id, a number from listBackups or a name. The current contents are backed
+// up first.
+func restoreBackup(db *sql.DB, path, id string, keep int) (backup, error) {
+	backups, err := listBackups(path)
+	if err != nil {
+		return backup{}, err
+	}
+
+	chosen, err := findBackup(backups, id)
+	if err != nil {
+		return backup{}, err
+	}
+
+	// Read the backup before the safety backup can rotate it away.
+	src, err := sql.Open("sqlite3", "file:"+chosen.Path+"?mode=ro")
+	if err != nil {
+		return backup{}, err
+	}
+	defer src.Close()
+	mem, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return backup{}, err
+	}
+	defer mem.Close()
+	mem.SetMaxOpenConns(1)
+	if err := copyDB(mem, src); err != nil {
+		return backup{}, err
+	}
+
+	if _, err := createBackup(db, path, "pre-restore", keep); err != nil {
+		return backup{}, err
+	}
+	return chosen, copyDB(db, mem)
+}
+
+// findBackup picks the backup named id, or numbered id in backups.
+func findBackup(backups []backup, id string) (backup, error) {
+	for _, b := range backups {
+		if b.Name == id {
+			return b, nil
+		}
+	}
+	if n, err := strconv.Atoi(id); err == nil && n >= 1 && n <= len(backups) {
+		return backups[n-1], nil
+	}
+	return backup{}, fmt.Errorf("no backup %q; see chkmrk backup list", id)
+}
+
+// openBackupTarget opens the current workspace for the backup commands,
+// which work on the database file.
+func openBackupTarget() (*sql.DB, func() error, string, Config, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, "", cfg, err
+	}
+	db, closeDB, err := openDB()
+	if err != nil {
+		return nil, nil, "", cfg, err
+	}
+	path, err := dbFile(db)
+	if err == nil && path == "" {
+		err = errors.New("backups need a workspace kept in a SQLite database")
+	}
+	if err != nil {
+		closeDB()
+		return nil, nil, "", cfg, err
+	}
+	return db, closeDB, path, cfg, nil
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	_, closeDB, path, _, err := openBackupTarget()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Println("No backups yet")
+	}
+	for i, b := range backups {
+		fmt.Printf("%3d  %-32s %s  %6d KB\n", i+1, b.Name, b.Time.Format("2006-01-02 15:04:05"), (b.Size+1023)/1024)
+	}
+	return nil
+}
+
+func runBackupCreate(cmd *cobra.Command, args []string) error {
+	db, closeDB, path, cfg, err := openBackupTarget()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	b, err := createBackup(db, path, "manual", cfg.Backups)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created backup %s\n", b.Name)
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	db, closeDB, path, cfg, err := openBackupTarget()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return err
+	}
+	b, err := findBackup(backups, args[0])
+	if err != nil {
+		return err
+	}
+	question := fmt.Sprintf("Replace the database with backup %s?", b.Name)
+	if err := confirmCLI(question, []string{"taken " + b.Time.Format("2006-01-02 15:04:05")}); err != nil {
+		return err
+	}
+
+	b, err = restoreBackup(db, path, b.Name, cfg.Backups)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored backup %s\n", b.Name)
+	return nil
+}
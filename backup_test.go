@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackups(t *testing.T) {
+	t.Setenv("CHKMRK_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	path := filepath.Join(t.TempDir(), "checklist.db")
+
+	db, closeDB, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addChecklist(db, "Release")
+	addItem(db, "Tag", false, 1)
+	closeDB()
+
+	// Reopening a database with data and no backups takes the daily one.
+	db, closeDB, err = openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+	backups, _ := listBackups(path)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0].Name, "-daily") {
+		t.Fatalf("listBackups() = %+v; expected one daily backup", backups)
+	}
+
+	addItem(db, "Push", false, 1)
+	for i := 0; i < 3; i++ {
+		if _, err := createBackup(db, path, "manual", 3); err != nil {
+			t.Fatalf("createBackup() error = %v", err)
+		}
+	}
+	backups, _ = listBackups(path)
+	if len(backups) != 3 {
+		t.Fatalf("listBackups() returned %d backups; expected rotation to keep 3", len(backups))
+	}
+	for index, b := range backups {
+		if !strings.Contains(b.Name, "-manual") {
+			t.Errorf("Test number %d -> backup %s kept; expected the oldest to be dropped", index, b.Name)
+		}
+	}
+
+	deleteItem(db, 1)
+	deleteItem(db, 2)
+	restored, err := restoreBackup(db, path, "1", 3)
+	if err != nil {
+		t.Fatalf("restoreBackup() error = %v", err)
+	}
+	if restored.Name != backups[0].Name {
+		t.Errorf("restoreBackup() restored %s; expected %s", restored.Name, backups[0].Name)
+	}
+	items, _ := getItemsByChecklistId(db, 1)
+	if len(items) != 2 {
+		t.Errorf("restored checklist has %d items; expected 2", len(items))
+	}
+
+	backups, _ = listBackups(path)
+	if !strings.HasSuffix(backups[0].Name, "-pre-restore") {
+		t.Errorf("newest backup is %s; expected the pre-restore backup", backups[0].Name)
+	}
+	if _, err := restoreBackup(db, path, "missing", 3); err == nil {
+		t.Error("restoreBackup(missing) succeeded; expected an error")
+	}
+}
+
+func TestMigrationBackup(t *testing.T) {
+	t.Setenv("CHKMRK_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	path := filepath.Join(t.TempDir(), "checklist.db")
+
+	db, closeDB, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addChecklist(db, "Release")
+	db.Exec(`PRAGMA user_version = 0`)
+	closeDB()
+
+	db, closeDB, err = openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+
+	backups, _ := listBackups(path)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0].Name, "-migration") {
+		t.Fatalf("listBackups() = %+v; expected one migration backup", backups)
+	}
+	if _, err := os.Stat(backups[0].Path); err != nil {
+		t.Error(err)
+	}
+	var version int
+	db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	if version != schemaVersion {
+		t.Errorf("user_version = %d; expected %d", version, schemaVersion)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rmCompletedFlag  bool
+	checkForceFlag   bool
+	initProjectFlag  bool
+	initMarkdownFlag bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create an empty checklist database for the workspace",
+	Args:  cobra.NoArgs,
+	RunE:  runInit,
+}
+
+var demoCmd = &cobra.Command{
+	Use:   "demo",
+	Short: "Load sample checklists into the workspace",
+	Args:  cobra.NoArgs,
+	RunE:  runDemo,
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check <item-id>...",
+	Short: "Mark items as completed",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setCompleted(args, true)
+	},
+}
+
+var uncheckCmd = &cobra.Command{
+	Use:   "uncheck <item-id>...",
+	Short: "Mark items as not completed",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setCompleted(args, false)
+	},
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <item-id>... | rm --completed <checklist>",
+	Short: "Move items to the trash, or delete them from markdown checklists",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runRm,
+}
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <item-id>... <checklist>",
+	Short: "Move items to another checklist",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return transferItems(args, moveItems, "Moved")
+	},
+}
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <item-id>... <checklist>",
+	Short: "Copy items into another checklist",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return transferItems(args, copyItems, "Copied")
+	},
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show <item-id>",
+	Short: "Print an item, its note and its command's runs",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runShow,
+}
+
+var editListCmd = &cobra.Command{
+	Use:   "edit-list <checklist>",
+	Short: "Edit a checklist as markdown in $EDITOR",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runEditList,
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&initProjectFlag, "project", false, "create a .chkmrk directory for project checklists in the working directory")
+	initCmd.Flags().BoolVar(&initMarkdownFlag, "markdown", false, "with --project, keep checklists as markdown files instead of a database")
+	checkCmd.Flags().BoolVar(&checkForceFlag, "force", false, "check items even if their prerequisites aren't done")
+	rmCmd.Flags().BoolVar(&rmCompletedFlag, "completed", false, "remove every completed item in the checklist")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	if initProjectFlag {
+		return initProject()
+	}
+
+	name, path, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("Workspace %s already has a database at %s\n", name, path)
+		return nil
+	}
+
+	_, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	fmt.Printf("Initialized empty workspace %s at %s\n", name, path)
+	return nil
+}
+
+func initProject() error {
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		return err
+	}
+	if initMarkdownFlag {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		db, closeDB, err := openMarkdownDir(projectDir)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+		if err := addChecklist(db, filepath.Base(cwd)); err != nil {
+			return err
+		}
+		fmt.Printf("Initialized project checklists as markdown in %s\n", projectDir)
+		return nil
+	}
+
+	path := projectDBPath(projectDir)
+	_, closeDB, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	fmt.Printf("Initialized project checklists at %s\n", path)
+	return nil
+}
+
+func runDemo(cmd *cobra.Command, args []string) error {
+	templates, err := readTemplates(demoTemplates, "templates/demo")
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	for _, t := range templates {
+		if _, err := addChecklistFromTemplate(db, t); err != nil {
+			return err
+		}
+		fmt.Printf("Added %s (%s)\n", t.Title, itemCount(len(t.Lines)))
+	}
+	return nil
+}
+
+func setCompleted(args []string, completed bool) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if completed && !checkForceFlag {
+		if err := checkBlocked(db, ids); err != nil {
+			return fmt.Errorf("%w; pass --force to check it anyway", err)
+		}
+	}
+	if err := updateItemsCompleted(db, ids, completed); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		item, err := getItemById(db, id)
+		if err != nil {
+			return err
+		}
+		RenderItemInBuffer(os.Stdout, item)
+	}
+	return nil
+}
+
+func runRm(cmd *cobra.Command, args []string) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if rmCompletedFlag {
+		if len(args) != 1 {
+			return fmt.Errorf("--completed takes exactly one checklist")
+		}
+		list, err := resolveChecklist(db, args[0])
+		if err != nil {
+			return err
+		}
+		items, err := getItemsByChecklistId(db, list.ID)
+		if err != nil {
+			return err
+		}
+		var preview []string
+		for _, item := range items {
+			if item.Completed {
+				preview = append(preview, item.Title)
+			}
+		}
+		if len(preview) > 0 {
+			if err := confirmCLI(deleteQuestion(db, fmt.Sprintf("%s from %s", completedCount(len(preview)), list.Title)), preview); err != nil {
+				return err
+			}
+		}
+		n, err := deleteCompletedItems(db, list.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Println(deletedMessage(db, fmt.Sprintf("%s from %s", completedCount(int(n)), list.Title)))
+		return nil
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	var preview []string
+	for _, id := range ids {
+		item, err := getItemById(db, id)
+		if err != nil {
+			return err
+		}
+		preview = append(preview, item.Title)
+	}
+	if err := confirmCLI(deleteQuestion(db, itemCount(len(ids))), preview); err != nil {
+		return err
+	}
+	if err := deleteItems(db, ids); err != nil {
+		return err
+	}
+	fmt.Println(deletedMessage(db, itemCount(len(ids))))
+	return nil
+}
+
+// transferItems applies a move or copy of the item IDs in args[:len-1] to
+// the checklist named by the last argument.
+func transferItems(args []string, transfer func(db *sql.DB, ids []int, checklist_id int) error, verb string) error {
+	ids, err := parseIDs(args[:len(args)-1])
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	list, err := resolveChecklist(db, args[len(args)-1])
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := getItemById(db, id); err != nil {
+			return err
+		}
+	}
+
+	if err := transfer(db, ids, list.ID); err != nil {
+		return err
+	}
+	fmt.Printf("%s %s to %s\n", verb, itemCount(len(ids)), list.Title)
+	return nil
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	item, err := getItemById(db, ids[0])
+	if err != nil {
+		return err
+	}
+
+	RenderItemInBuffer(os.Stdout, item)
+	if item.Note != "" {
+		note, err := glamour.Render(item.Note, "auto")
+		if err != nil {
+			return err
+		}
+		fmt.Print(note)
+	}
+	if item.Command != "" {
+		fmt.Printf("\n$ %s\n", item.Command)
+	}
+	runs, err := getRuns(db, item.ID)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		fmt.Printf("  %s  exit %d  %s  %s\n", run.StartedAt.Format("2006-01-02 15:04"), run.ExitCode,
+			run.FinishedAt.Sub(run.StartedAt), run.Command)
+	}
+	return nil
+}
+
+func runEditList(cmd *cobra.Command, args []string) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	list, err := resolveChecklist(db, args[0])
+	if err != nil {
+		return err
+	}
+	items, err := getItemsByChecklistId(db, list.ID)
+	if err != nil {
+		return err
+	}
+
+	path, err := writeChecklistFile(list.Title, items)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	editor := editorCmd(path)
+	editor.Stdin, editor.Stdout, editor.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editor.Run(); err != nil {
+		return err
+	}
+
+	diff, err := applyChecklistFile(db, list.ID, items, path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", list.Title, diff)
+	return nil
+}
+
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item id %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// resolveChecklist finds a checklist by ID or, failing that, by title.
+func resolveChecklist(db *sql.DB, ref string) (Checklist, error) {
+	lists, err := getChecklists(db)
+	if err != nil {
+		return Checklist{}, err
+	}
+
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, list := range lists {
+			if list.ID == id {
+				return list, nil
+			}
+		}
+	}
+	for _, list := range lists {
+		if list.Title == ref {
+			return list, nil
+		}
+	}
+	return Checklist{}, fmt.Errorf("no checklist matching %q", ref)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestBulkCommands(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("CHKMRK_CONFIG", filepath.Join(root, "config.json"))
+	chdir(t, root)
+	defer func(yes, completed bool) { yesFlag, rmCompletedFlag = yes, completed }(yesFlag, rmCompletedFlag)
+	yesFlag = true
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addChecklist(db, "Release")
+	addChecklist(db, "Review")
+	for _, title := range []string{"Tag", "Test", "Push"} {
+		addItem(db, title, false, 1)
+	}
+	addItem(db, "Read diff", true, 2)
+	closeDB()
+
+	tests := []struct {
+		command   *cobra.Command
+		args      []string
+		completed bool
+		fails     bool
+		expected  []string
+	}{
+		{checkCmd, []string{"1", "3"}, false, false, []string{"Release [x] Tag", "Release [ ] Test", "Release [x] Push", "Review [x] Read diff"}},
+		// One ID that isn't there leaves every item as it was.
+		{checkCmd, []string{"2", "99"}, false, true, []string{"Release [x] Tag", "Release [ ] Test", "Release [x] Push", "Review [x] Read diff"}},
+		{uncheckCmd, []string{"3", "99"}, false, true, []string{"Release [x] Tag", "Release [ ] Test", "Release [x] Push", "Review [x] Read diff"}},
+		{uncheckCmd, []string{"3"}, false, false, []string{"Release [x] Tag", "Release [ ] Test", "Release [ ] Push", "Review [x] Read diff"}},
+		{rmCmd, []string{"Release"}, true, false, []string{"Release [ ] Test", "Release [ ] Push", "Review [x] Read diff"}},
+		{rmCmd, []string{"2", "99"}, false, true, []string{"Release [ ] Test", "Release [ ] Push", "Review [x] Read diff"}},
+		{rmCmd, []string{"2", "4"}, false, false, []string{"Release [ ] Push"}},
+	}
+
+	for index, test := range tests {
+		rmCompletedFlag = test.completed
+		if err := test.command.RunE(test.command, test.args); (err != nil) != test.fails {
+			t.Errorf("Test number %d -> %s %v error = %v; expected failure %v", index, test.command.Name(), test.args, err, test.fails)
+		}
+
+		db, closeDB, err := openDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := itemStates(t, db); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test number %d -> %s %v left %q; expected %q", index, test.command.Name(), test.args, actual, test.expected)
+		}
+		closeDB()
+	}
+}
@@ -8,12 +8,28 @@ import (
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "hugo",
-	Short: "Hugo is a very fast static site generator",
-	Long: `A Fast and Flexible Static Site Generator built with
-                love by spf13 and friends in Go.
-                Complete documentation is available at http://hugo.spf13.com`,
-	Run: runProcess,
+	Use:   "chkmrk",
+	Short: "ChkMrk is a checklist manager for the terminal",
+	Long: `ChkMrk keeps checklists in a local database.
+Run it without a command to open the interactive view, or use one
+of the commands below from scripts.`,
+	Run:           runProcess,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+// Process is run when chkmrk is called without a subcommand.
+var Process func()
+
+// Workspace is the workspace named by --workspace, empty for the default.
+var Workspace string
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&Workspace, "workspace", "w", "", "workspace to open instead of the default")
+}
+
+func AddCommand(cmds ...*cobra.Command) {
+	rootCmd.AddCommand(cmds...)
 }
 
 func Execute() {
@@ -24,5 +40,7 @@ func Execute() {
 }
 
 func runProcess(cmd *cobra.Command, args []string) {
-
+	if Process != nil {
+		Process()
+	}
 }
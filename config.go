@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type Config struct {
+	// Keymap selects the preset keymap: "default", "vim" or "emacs".
+	Keymap string `json:"keymap"`
+	// Keys overrides individual bindings by action name, e.g. {"delete": ["d"]}.
+	Keys map[string][]string `json:"keys"`
+	// Workspaces maps workspace names to their database files.
+	Workspaces map[string]string `json:"workspaces"`
+	// DefaultWorkspace is opened when no --workspace is given.
+	DefaultWorkspace string `json:"default_workspace"`
+	// Backups is how many automatic and manual backups to keep per
+	// database. Zero means defaultBackups.
+	Backups int `json:"backups"`
+	// TrashDays is how long deleted checklists and items stay in the
+	// trash before they are purged. Zero means defaultTrashDays.
+	TrashDays int `json:"trash_days"`
+}
+
+func configPath() (string, error) {
+	if path := os.Getenv("CHKMRK_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chkmrk", "config.json"), nil
+}
+
+func loadConfig() (Config, error) {
+	var cfg Config
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// confirmPreviewLines is how much of what an action affects the
+// confirmation shows before summing up the rest.
+const confirmPreviewLines = 8
+
+var yesFlag bool
+
+var errCancelled = errors.New("cancelled")
+
+func init() {
+	for _, c := range []*cobra.Command{rmCmd, trashPurgeCmd, trashEmptyCmd, backupRestoreCmd} {
+		c.Flags().BoolVarP(&yesFlag, "yes", "y", false, "don't ask for confirmation")
+	}
+}
+
+// openConfirm asks the user to confirm question, listing preview: what the
+// action will change. onConfirm runs only on yes, and the command it
+// returns with it. The action should capture the IDs it works on now, so it
+// changes exactly what the preview showed.
+func (m *model) openConfirm(question string, preview []string, onConfirm func(m *model) tea.Cmd) {
+	m.confirming = true
+	m.confirmQuestion = question
+	m.confirmPreview = preview
+	m.onConfirm = onConfirm
+}
+
+func ConfirmAction(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+
+		case "y", "Y":
+			m.confirming = false
+			return m, m.onConfirm(&m)
+
+		case "n", "N", "esc", "enter":
+			m.confirming = false
+
+		}
+	}
+
+	return m, nil
+}
+
+func ConfirmView(m model) string {
+	s := fmt.Sprintf("\n  %s\n\n", m.confirmQuestion)
+	for _, line := range previewLines(m.confirmPreview) {
+		s += "    " + line + "\n"
+	}
+	s += "\n(y to confirm, n or esc to cancel)\n"
+
+	return s
+}
+
+// previewLines cuts preview down to confirmPreviewLines.
+func previewLines(preview []string) []string {
+	if len(preview) <= confirmPreviewLines {
+		return preview
+	}
+	lines := append([]string(nil), preview[:confirmPreviewLines-1]...)
+	return append(lines, fmt.Sprintf("... and %d more", len(preview)-len(lines)))
+}
+
+// confirmCLI asks on the terminal before a command changes what preview
+// lists, unless --yes was given. Without a terminal to ask on it fails, so
+// scripts have to say --yes.
+func confirmCLI(question string, preview []string) error {
+	if yesFlag {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%s Pass --yes to confirm without a terminal", question)
+	}
+
+	for _, line := range previewLines(preview) {
+		fmt.Fprintln(os.Stderr, "  "+line)
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errCancelled
+}
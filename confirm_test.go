@@ -0,0 +1,151 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestConfirmDelete(t *testing.T) {
+	tests := []struct {
+		keys      []string
+		remaining []string
+		cursor    int
+	}{
+		{[]string{"x", "n"}, []string{"Tag", "Test", "Push", "Announce"}, 2},
+		{[]string{"x", "esc"}, []string{"Tag", "Test", "Push", "Announce"}, 2},
+		{[]string{"x", "q", "n"}, []string{"Tag", "Test", "Push", "Announce"}, 2},
+		{[]string{"x", "y"}, []string{"Tag", "Test", "Announce"}, 2},
+		{[]string{"V", "k", "x", "y"}, []string{"Tag", "Announce"}, 1},
+		{[]string{"G", "x", "y"}, []string{"Tag", "Test", "Push"}, 2},
+	}
+
+	for index, test := range tests {
+		db := newTestDB(t)
+		addChecklist(db, "Release")
+		for _, title := range []string{"Tag", "Test", "Push", "Announce"} {
+			addItem(db, title, false, 1)
+		}
+
+		m := initialModel(db, defaultKeyMap())
+		m.openChecklist()
+		m.moveCursor(2)
+		for _, k := range test.keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			if k == "esc" {
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			}
+			next, _ := m.Update(msg)
+			m = next.(model)
+		}
+
+		if m.confirming {
+			t.Errorf("Test number %d -> still confirming after %v", index, test.keys)
+		}
+		if !reflect.DeepEqual(m.choices, test.remaining) || m.cursor != test.cursor {
+			t.Errorf("Test number %d -> after %v, items = %v with cursor %d; expected %v with cursor %d",
+				index, test.keys, m.choices, m.cursor, test.remaining, test.cursor)
+		}
+	}
+}
+
+func TestConfirmTransfer(t *testing.T) {
+	tests := []struct {
+		keys     []string
+		question string
+		release  []string
+		later    []string
+	}{
+		{[]string{"m", "j", "enter", "n"}, "Move 1 item to Later?", []string{"Tag", "Test", "Push"}, nil},
+		{[]string{"m", "j", "enter", "y"}, "Move 1 item to Later?", []string{"Tag", "Test"}, []string{"Push"}},
+		{[]string{"V", "k", "m", "j", "enter", "y"}, "Move 2 items to Later?", []string{"Tag"}, []string{"Test", "Push"}},
+		{[]string{"V", "k", "c", "j", "enter", "esc"}, "Copy 2 items to Later?", []string{"Tag", "Test", "Push"}, nil},
+		{[]string{"V", "k", "c", "j", "enter", "y"}, "Copy 2 items to Later?", []string{"Tag", "Test", "Push"}, []string{"Test", "Push"}},
+	}
+
+	for index, test := range tests {
+		db := newTestDB(t)
+		addChecklist(db, "Release")
+		addChecklist(db, "Later")
+		for _, title := range []string{"Tag", "Test", "Push"} {
+			addItem(db, title, false, 1)
+		}
+
+		m := initialModel(db, defaultKeyMap())
+		m.openChecklist()
+		m.moveCursor(2)
+		var question string
+		for _, k := range test.keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			switch k {
+			case "esc":
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			next, _ := m.Update(msg)
+			m = next.(model)
+			if m.confirming {
+				question = m.confirmQuestion
+			}
+		}
+
+		if question != test.question {
+			t.Errorf("Test number %d -> after %v, asked %q; expected %q", index, test.keys, question, test.question)
+		}
+		for id, expected := range map[int][]string{1: test.release, 2: test.later} {
+			var titles []string
+			items, _ := getItemsByChecklistId(db, id)
+			for _, item := range items {
+				titles = append(titles, item.Title)
+			}
+			if !reflect.DeepEqual(titles, expected) {
+				t.Errorf("Test number %d -> after %v, checklist %d = %v; expected %v", index, test.keys, id, titles, expected)
+			}
+		}
+	}
+}
+
+func TestItemCount(t *testing.T) {
+	tests := []struct {
+		n         int
+		items     string
+		completed string
+	}{
+		{0, "0 items", "0 completed items"},
+		{1, "1 item", "1 completed item"},
+		{2, "2 items", "2 completed items"},
+	}
+	for index, test := range tests {
+		if got := itemCount(test.n); got != test.items {
+			t.Errorf("Test number %d -> itemCount(%d) = %q; expected %q", index, test.n, got, test.items)
+		}
+		if got := completedCount(test.n); got != test.completed {
+			t.Errorf("Test number %d -> completedCount(%d) = %q; expected %q", index, test.n, got, test.completed)
+		}
+	}
+}
+
+func TestPreviewLines(t *testing.T) {
+	tests := []struct {
+		preview  int
+		expected int
+		last     string
+	}{
+		{0, 0, ""},
+		{confirmPreviewLines, confirmPreviewLines, "item"},
+		{confirmPreviewLines + 5, confirmPreviewLines, "... and 6 more"},
+	}
+
+	for index, test := range tests {
+		preview := make([]string, test.preview)
+		for i := range preview {
+			preview[i] = "item"
+		}
+		actual := previewLines(preview)
+		if len(actual) != test.expected || len(actual) > 0 && actual[len(actual)-1] != test.last {
+			t.Errorf("Test number %d -> previewLines(%d lines) = %v; expected %d lines ending %q", index, test.preview, actual, test.expected, test.last)
+		}
+	}
+}
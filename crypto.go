@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+// Titles and notes of an encrypted database are stored as sealedPrefix
+// followed by the base64 of an AES-256-GCM nonce and ciphertext. The key is
+// derived from a passphrase with PBKDF2-HMAC-SHA256.
+const (
+	sealedPrefix  = "enc1:"
+	keyCheckValue = "chkmrk"
+)
+
+// kdfIterations is the PBKDF2 work factor for new keys. Each database
+// records the count its key was made with.
+var kdfIterations = 600000
+
+// keyring holds the cipher of every open encrypted database, keyed by its
+// *sql.DB.
+var keyring sync.Map
+
+// allowPrompt is cleared once the TUI owns the terminal.
+var allowPrompt = true
+
+var errWrongPassphrase = errors.New("wrong passphrase")
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the workspace database",
+}
+
+var dbEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt checklist and item titles and notes with a passphrase",
+	Long: `Encrypt the titles and notes in the workspace database with a passphrase,
+read from $CHKMRK_PASSPHRASE or asked for. Afterwards chkmrk reads the
+passphrase from $CHKMRK_PASSPHRASE, else from the file named by
+$CHKMRK_KEYFILE, else asks for it. Machines that sync a
+workspace share one key: the first to sync sets it, a plain database takes
+it on when it syncs, and a database encrypted under another key is refused
+until it is decrypted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changePassphrase(setPassphrase)
+	},
+}
+
+var dbRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Change the passphrase of an encrypted database",
+	Long: `Re-encrypt the database under a new passphrase. The current one is read
+like for any command; the new one from $CHKMRK_NEW_PASSPHRASE or asked for.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changePassphrase(replacePassphrase)
+	},
+}
+
+var dbDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store titles and notes in plain text again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changePassphrase(removePassphrase)
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(dbEncryptCmd, dbRekeyCmd, dbDecryptCmd)
+}
+
+// passphraseChange is what db encrypt, rekey and decrypt do.
+type passphraseChange int
+
+const (
+	setPassphrase passphraseChange = iota
+	replacePassphrase
+	removePassphrase
+)
+
+func changePassphrase(change passphraseChange) error {
+	// Opening an encrypted database checks its current passphrase.
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if file, err := dbFile(db); err != nil || file == "" {
+		return fmt.Errorf("encryption needs a workspace kept in a SQLite database")
+	}
+	k, err := getKey(db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case change == setPassphrase && k != nil:
+		return errors.New("the database is already encrypted; chkmrk db rekey changes its passphrase")
+	case change != setPassphrase && k == nil:
+		return errors.New("the database isn't encrypted; chkmrk db encrypt encrypts it")
+	case k != nil && dbCipher(db) == nil:
+		return errors.New("the database is encrypted but wasn't unlocked with its passphrase")
+	}
+
+	if change == removePassphrase {
+		if err := rekeyDB(db, ""); err != nil {
+			return err
+		}
+		fmt.Println("Decrypted the database")
+		return nil
+	}
+
+	env := "CHKMRK_PASSPHRASE"
+	if change == replacePassphrase {
+		env = "CHKMRK_NEW_PASSPHRASE"
+	}
+	passphrase, err := readNewPassphrase(env)
+	if err != nil {
+		return err
+	}
+	if err := rekeyDB(db, passphrase); err != nil {
+		return err
+	}
+	if change == replacePassphrase {
+		fmt.Println("Re-encrypted the database with the new passphrase")
+	} else {
+		fmt.Println("Encrypted the database")
+	}
+	return nil
+}
+
+// dbFile returns the file behind db, or "" for an in-memory database.
+func dbFile(db *sql.DB) (string, error) {
+	var seq int
+	var name, file string
+	err := db.QueryRow(`PRAGMA database_list;`).Scan(&seq, &name, &file)
+	return file, err
+}
+
+// readPassphrase finds the passphrase of an encrypted database.
+func readPassphrase() (string, error) {
+	if passphrase := os.Getenv("CHKMRK_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if path := os.Getenv("CHKMRK_KEYFILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return promptPassphrase("Passphrase: ")
+}
+
+// readNewPassphrase reads a passphrase to encrypt with from the variable
+// env, else asks for it twice.
+func readNewPassphrase(env string) (string, error) {
+	if passphrase := os.Getenv(env); passphrase != "" {
+		return passphrase, nil
+	}
+	passphrase, err := promptPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	again, err := promptPassphrase("Repeat new passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != again {
+		return "", errors.New("passphrases don't match")
+	}
+	if passphrase == "" {
+		return "", errors.New("empty passphrase")
+	}
+	return passphrase, nil
+}
+
+func promptPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !allowPrompt || !term.IsTerminal(fd) {
+		return "", errors.New("the database is encrypted; set CHKMRK_PASSPHRASE or CHKMRK_KEYFILE")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(passphrase), err
+}
+
+// deriveKey derives the AES-256 key of passphrase.
+func deriveKey(passphrase string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, 32, sha256.New)
+}
+
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(passphrase, salt, iterations))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealWith(aead cipher.AEAD, text string) (string, error) {
+	if aead == nil || text == "" {
+		return text, nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(text), nil)), nil
+}
+
+// unsealWith decrypts text with aead. Without one the database isn't
+// encrypted, so text is plain even if it looks sealed.
+func unsealWith(aead cipher.AEAD, text string) (string, error) {
+	data, ok := strings.CutPrefix(text, sealedPrefix)
+	if !ok || aead == nil {
+		return text, nil
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", errWrongPassphrase
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errWrongPassphrase
+	}
+	return string(plain), nil
+}
+
+func dbCipher(db *sql.DB) cipher.AEAD {
+	if aead, ok := keyring.Load(db); ok {
+		return aead.(cipher.AEAD)
+	}
+	return nil
+}
+
+// seal encrypts text for storing in db if db is encrypted.
+func seal(db *sql.DB, text string) (string, error) {
+	return sealWith(dbCipher(db), text)
+}
+
+// unseal decrypts text read from db if it was stored encrypted.
+func unseal(db *sql.DB, text string) (string, error) {
+	return unsealWith(dbCipher(db), text)
+}
+
+func unsealItem(db *sql.DB, item *Item) error {
+	var err error
+	if item.Title, err = unseal(db, item.Title); err != nil {
+		return err
+	}
+	if item.Note, err = unseal(db, item.Note); err != nil {
+		return err
+	}
+	if item.Command, err = unseal(db, item.Command); err != nil {
+		return err
+	}
+	item.Tags, err = unseal(db, item.Tags)
+	return err
+}
+
+// dbKey is what a database's key is derived with besides the passphrase,
+// and a value sealed under the key to tell a wrong passphrase.
+type dbKey struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Check      string `json:"check"`
+}
+
+// getKey returns the key parameters of db, or nil if it isn't encrypted.
+func getKey(db *sql.DB) (*dbKey, error) {
+	var k dbKey
+	err := db.QueryRow(`SELECT salt, iterations, check_value FROM encryption`).Scan(&k.Salt, &k.Iterations, &k.Check)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// same reports whether k and other derive the same key from a passphrase.
+// A nil key, no encryption, is only the same as another nil key.
+func (k *dbKey) same(other *dbKey) bool {
+	if k == nil || other == nil {
+		return k == other
+	}
+	return k.Iterations == other.Iterations && bytes.Equal(k.Salt, other.Salt)
+}
+
+// open returns the cipher of passphrase under k.
+func (k *dbKey) open(passphrase string) (cipher.AEAD, error) {
+	aead, err := newAEAD(passphrase, k.Salt, k.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if value, err := unsealWith(aead, k.Check); err != nil || value != keyCheckValue {
+		return nil, errWrongPassphrase
+	}
+	return aead, nil
+}
+
+// unlockDB asks for the passphrase if db is encrypted and puts its cipher
+// on the keyring.
+func unlockDB(db *sql.DB) error {
+	k, err := getKey(db)
+	if k == nil || err != nil {
+		return err
+	}
+
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return err
+	}
+	aead, err := k.open(passphrase)
+	if err != nil {
+		return err
+	}
+	keyring.Store(db, aead)
+	return nil
+}
+
+// lockDB forgets db's cipher.
+func lockDB(db *sql.DB) {
+	keyring.Delete(db)
+}
+
+// closeSealed returns a close function for db that also forgets its cipher.
+func closeSealed(db *sql.DB) func() error {
+	return func() error {
+		lockDB(db)
+		return db.Close()
+	}
+}
+
+// rekeyDB re-encrypts db under passphrase with a new salt, or decrypts it
+// if passphrase is empty.
+func rekeyDB(db *sql.DB, passphrase string) error {
+	if passphrase == "" {
+		return rekeyTo(db, nil, nil)
+	}
+	k := &dbKey{Salt: make([]byte, 16), Iterations: kdfIterations}
+	if _, err := rand.Read(k.Salt); err != nil {
+		return err
+	}
+	to, err := newAEAD(passphrase, k.Salt, k.Iterations)
+	if err != nil {
+		return err
+	}
+	if k.Check, err = sealWith(to, keyCheckValue); err != nil {
+		return err
+	}
+	return rekeyTo(db, k, to)
+}
+
+// rekeyTo re-encrypts every title and note, including those in the sync
+// log, with to, the cipher of k, or decrypts them if k is nil. It runs in
+// one transaction with the sync triggers off, since nothing changes that
+// other machines need to hear about.
+func rekeyTo(db *sql.DB, k *dbKey, to cipher.AEAD) error {
+	from := dbCipher(db)
+	reseal := func(text string) (string, error) {
+		plain, err := unsealWith(from, text)
+		if err != nil {
+			return "", err
+		}
+		return sealWith(to, plain)
+	}
+
+	err := withTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE sync_state SET applying = 1`); err != nil {
+			return err
+		}
+
+		columns := []struct{ table, column, where string }{
+			{"checklists", "title", ""},
+			{"items", "title", ""},
+			{"items", "note", ""},
+			{"items", "command", ""},
+			{"items", "tags", ""},
+			{"runs", "command", ""},
+			{"runs", "output", ""},
+			{"ops", "value", `WHERE field IN ('title', 'note', 'command', 'tags') OR kind = 'checklist' AND field = 'create'`},
+		}
+		for _, c := range columns {
+			if err := resealColumn(tx, c.table, c.column, c.where, reseal); err != nil {
+				return err
+			}
+		}
+		if err := resealItemCreates(tx, reseal); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM encryption`); err != nil {
+			return err
+		}
+		if k != nil {
+			query := `INSERT INTO encryption (salt, iterations, check_value) VALUES (?, ?, ?)`
+			if _, err := tx.Exec(query, k.Salt, k.Iterations, k.Check); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`UPDATE sync_state SET applying = 0`)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if to != nil {
+		keyring.Store(db, to)
+	} else {
+		lockDB(db)
+	}
+	return nil
+}
+
+func resealColumn(tx *sql.Tx, table, column, where string, reseal func(string) (string, error)) error {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT rowid, %s FROM %s %s`, column, table, where))
+	if err != nil {
+		return err
+	}
+	values := map[int64]string{}
+	for rows.Next() {
+		var rowid int64
+		var value string
+		if err := rows.Scan(&rowid, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		values[rowid] = value
+	}
+	rows.Close()
+
+	query := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE rowid = ?`, table, column)
+	for rowid, value := range values {
+		resealed, err := reseal(value)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, resealed, rowid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resealItemCreates reseals the title and note inside item create ops.
+func resealItemCreates(tx *sql.Tx, reseal func(string) (string, error)) error {
+	return resealColumn(tx, "ops", "value", `WHERE kind = 'item' AND field = 'create'`, func(value string) (string, error) {
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return "", err
+		}
+		for _, key := range []string{"title", "note"} {
+			if text, ok := v[key].(string); ok {
+				resealed, err := reseal(text)
+				if err != nil {
+					return "", err
+				}
+				v[key] = resealed
+			}
+		}
+		data, err := json.Marshal(v)
+		return string(data), err
+	})
+}
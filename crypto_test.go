@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	tests := []struct {
+		iterations int
+		expected   string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+
+	for index, test := range tests {
+		actual := hex.EncodeToString(deriveKey("password", []byte("salt"), test.iterations))
+		if actual != test.expected {
+			t.Errorf("Test number %d -> deriveKey(%d) = %s; expected %s", index, test.iterations, actual, test.expected)
+		}
+	}
+}
+
+func TestEncryptedDB(t *testing.T) {
+	defer func(n int) { kdfIterations = n }(kdfIterations)
+	kdfIterations = 1000
+	allowPrompt = false
+	defer func() { allowPrompt = true }()
+
+	path := filepath.Join(t.TempDir(), "checklist.db")
+	db, closeDB, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addChecklist(db, "Incident 42")
+	addItem(db, "Rotate keys", false, 1)
+	updateItemNote(db, 1, "db password leaked")
+
+	if err := rekeyDB(db, "correct horse"); err != nil {
+		t.Fatalf("rekeyDB() error = %v", err)
+	}
+	addItem(db, "Write postmortem", false, 1)
+	closeDB()
+
+	// Reopen without the passphrase, with a wrong one and with the right one.
+	if _, _, err := openSQLite(path); err == nil {
+		t.Errorf("openSQLite() without a passphrase succeeded")
+	}
+	t.Setenv("CHKMRK_PASSPHRASE", "wrong")
+	if _, _, err := openSQLite(path); err != errWrongPassphrase {
+		t.Errorf("openSQLite() with a wrong passphrase error = %v; expected %v", err, errWrongPassphrase)
+	}
+	t.Setenv("CHKMRK_PASSPHRASE", "correct horse")
+	db, closeDB, err = openSQLite(path)
+	if err != nil {
+		t.Fatalf("openSQLite() with the passphrase error = %v", err)
+	}
+	defer closeDB()
+
+	items, _ := getItemsByChecklistId(db, 1)
+	if len(items) != 2 || items[0].Title != "Rotate keys" || items[0].Note != "db password leaked" || items[1].Title != "Write postmortem" {
+		t.Errorf("items = %+v; expected the titles and note decrypted", items)
+	}
+
+	// Nothing readable is left in the tables or the sync log.
+	for _, query := range []string{
+		`SELECT title FROM checklists`,
+		`SELECT title || note FROM items`,
+		`SELECT value FROM ops WHERE field IN ('title', 'note', 'create')`,
+	} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var value string
+			rows.Scan(&value)
+			for _, secret := range []string{"Incident", "Rotate", "leaked", "postmortem"} {
+				if strings.Contains(value, secret) {
+					t.Errorf("%s -> %q contains %q", query, value, secret)
+				}
+			}
+		}
+		rows.Close()
+	}
+
+	if err := rekeyDB(db, ""); err != nil {
+		t.Fatalf("rekeyDB(\"\") error = %v", err)
+	}
+	var title string
+	db.QueryRow(`SELECT title FROM items WHERE id = 1`).Scan(&title)
+	if title != "Rotate keys" {
+		t.Errorf("title after decrypting = %q; expected %q", title, "Rotate keys")
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	defer func(n int) { kdfIterations = n }(kdfIterations)
+	kdfIterations = 1000
+	allowPrompt = false
+	defer func() { allowPrompt = true }()
+	root := t.TempDir()
+	t.Setenv("CHKMRK_CONFIG", filepath.Join(root, "config.json"))
+	chdir(t, root)
+
+	tests := []struct {
+		change     passphraseChange
+		passphrase string
+		expected   string
+	}{
+		{replacePassphrase, "", "isn't encrypted"},
+		{removePassphrase, "", "isn't encrypted"},
+		{setPassphrase, "correct horse", ""},
+		{setPassphrase, "correct horse", "already encrypted"},
+		{replacePassphrase, "wrong", errWrongPassphrase.Error()},
+		{replacePassphrase, "correct horse", ""},
+		// The new passphrase is the one that opens it now.
+		{removePassphrase, "correct horse", errWrongPassphrase.Error()},
+		{removePassphrase, "battery staple", ""},
+	}
+	t.Setenv("CHKMRK_NEW_PASSPHRASE", "battery staple")
+	for index, test := range tests {
+		t.Setenv("CHKMRK_PASSPHRASE", test.passphrase)
+		err := changePassphrase(test.change)
+		if test.expected == "" && err != nil || test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)) {
+			t.Errorf("Test number %d -> changePassphrase(%d) error = %v; expected %q", index, test.change, err, test.expected)
+		}
+	}
+}
+
+func TestPlainTitleLooksSealed(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, sealedPrefix+"foo")
+	addItem(db, sealedPrefix+"bar", false, 1)
+
+	lists, err := getChecklists(db)
+	if err != nil || len(lists) != 1 || lists[0].Title != sealedPrefix+"foo" {
+		t.Errorf("getChecklists() = %v, %v; expected the title as written", lists, err)
+	}
+	items, err := getItemsByChecklistId(db, 1)
+	if err != nil || len(items) != 1 || items[0].Title != sealedPrefix+"bar" {
+		t.Errorf("getItemsByChecklistId() = %v, %v; expected the title as written", items, err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+// The cursor is an index into the list the current layout shows: the
+// checklists, the open checklist's items or the trash. Lists change under
+// it (deletes, reloads, other processes), so nothing indexes a list with
+// m.cursor directly. setCursor and moveCursor keep it on a row, and the
+// cursor* accessors report whether there is a row under it at all.
+
+// listLen returns the length of the list the cursor moves over in the
+// current layout.
+func (m model) listLen() int {
+	switch m.layout {
+	case Checklists:
+		return len(m.checklists)
+	case Trash:
+		return len(m.trash)
+	}
+	return len(m.choices)
+}
+
+// clampCursor returns i moved onto a list of n rows: the nearest end when
+// it's outside, and 0 when the list is empty.
+func clampCursor(i, n int) int {
+	return max(min(i, n-1), 0)
+}
+
+// setCursor puts the cursor on row i, clamped to the list, and scrolls the
+// window so it stays visible. A visual selection's anchor is clamped too.
+func (m *model) setCursor(i int) {
+	m.cursor = clampCursor(i, m.listLen())
+	m.anchor = clampCursor(m.anchor, m.listLen())
+	m.offset = clampOffset(m.cursor, m.offset, m.listHeight())
+}
+
+// moveCursor moves the cursor by delta rows, clamped to the list.
+func (m *model) moveCursor(delta int) {
+	m.setCursor(m.cursor + delta)
+}
+
+func (m model) cursorChecklist() (Checklist, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.checklists) {
+		return Checklist{}, false
+	}
+	return m.checklists[m.cursor], true
+}
+
+func (m model) cursorItem() (Item, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.items) {
+		return Item{}, false
+	}
+	return m.items[m.cursor], true
+}
+
+func (m model) cursorTrash() (trashEntry, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.trash) {
+		return trashEntry{}, false
+	}
+	return m.trash[m.cursor], true
+}
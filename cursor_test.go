@@ -0,0 +1,145 @@
+package main
+
+import (
+	"database/sql"
+	"math/rand"
+	"sort"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestClampCursor(t *testing.T) {
+	tests := []struct {
+		i, n     int
+		expected int
+	}{
+		{0, 0, 0},
+		{3, 0, 0},
+		{-1, 0, 0},
+		{-1, 5, 0},
+		{2, 5, 2},
+		{5, 5, 4},
+		{9, 5, 4},
+	}
+
+	for index, test := range tests {
+		if actual := clampCursor(test.i, test.n); actual != test.expected {
+			t.Errorf("Test number %d -> clampCursor(%d, %d) = %d; expected %d", index, test.i, test.n, actual, test.expected)
+		}
+	}
+}
+
+var namedKeys = map[string]tea.KeyType{
+	"up": tea.KeyUp, "down": tea.KeyDown, "left": tea.KeyLeft, "right": tea.KeyRight,
+	"pgup": tea.KeyPgUp, "pgdown": tea.KeyPgDown, "home": tea.KeyHome, "end": tea.KeyEnd,
+	"tab": tea.KeyTab, "enter": tea.KeyEnter, "esc": tea.KeyEsc, " ": tea.KeySpace,
+	"shift+up": tea.KeyShiftUp, "shift+down": tea.KeyShiftDown,
+	"ctrl+u": tea.KeyCtrlU, "ctrl+d": tea.KeyCtrlD, "ctrl+s": tea.KeyCtrlS, "ctrl+c": tea.KeyCtrlC,
+}
+
+// fuzzKeys returns every key the default keymap binds, plus the keys the
+// modals and inputs answer to. Switching workspaces and the $EDITOR
+// actions are left out: they open real files.
+func fuzzKeys() []tea.KeyMsg {
+	seen := map[string]bool{"y": true, "n": true, "a": true, "esc": true, "enter": true, "ctrl+s": true}
+	keys := defaultKeyMap()
+	for name, b := range keys.bindings() {
+		if name == "workspace" || name == "noteeditor" || name == "editlist" {
+			continue
+		}
+		for _, k := range b.Keys() {
+			seen[k] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for k := range seen {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var msgs []tea.KeyMsg
+	for _, k := range names {
+		if typ, ok := namedKeys[k]; ok {
+			msgs = append(msgs, tea.KeyMsg{Type: typ})
+		} else if utf8.RuneCountInString(k) == 1 {
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		}
+	}
+	return msgs
+}
+
+// runKeys feeds msgs to a model on db and fails if it panics or the cursor
+// ever leaves the list.
+func runKeys(t *testing.T, db *sql.DB, msgs []tea.Msg) {
+	t.Helper()
+	var m tea.Model = initialModel(db, defaultKeyMap())
+	for step, msg := range msgs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("step %d (%v) panicked: %v", step, msg, r)
+				}
+			}()
+			m, _ = m.Update(msg)
+			m.View()
+		}()
+
+		// The text input handlers return *model.
+		var state model
+		switch next := m.(type) {
+		case model:
+			state = next
+		case *model:
+			state = *next
+		}
+		if n := state.listLen(); state.cursor != clampCursor(state.cursor, n) {
+			t.Fatalf("step %d (%v) left the cursor at %d in a list of %d", step, msg, state.cursor, n)
+		}
+	}
+}
+
+func newFuzzDB(t *testing.T, filled bool) *sql.DB {
+	db := newTestDB(t)
+	if filled {
+		addChecklist(db, "Release")
+		addChecklist(db, "Empty")
+		for _, title := range []string{"Tag", "Test", "Push"} {
+			addItem(db, title, false, 1)
+		}
+	}
+	return db
+}
+
+func TestRandomKeys(t *testing.T) {
+	keys := fuzzKeys()
+	sizes := []tea.Msg{tea.WindowSizeMsg{Width: 60, Height: 12}, tea.WindowSizeMsg{Width: 120, Height: 30}}
+
+	for seed := int64(0); seed < 40; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		msgs := []tea.Msg{sizes[r.Intn(len(sizes))]}
+		for i := 0; i < 300; i++ {
+			if r.Intn(50) == 0 {
+				msgs = append(msgs, sizes[r.Intn(len(sizes))])
+				continue
+			}
+			msgs = append(msgs, keys[r.Intn(len(keys))])
+		}
+		runKeys(t, newFuzzDB(t, seed%4 != 0), msgs)
+	}
+}
+
+func FuzzKeys(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	f.Add([]byte("xyxyxyxy"))
+
+	keys := fuzzKeys()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msgs := make([]tea.Msg, len(data))
+		for i, b := range data {
+			msgs[i] = keys[int(b)%len(keys)]
+		}
+		runKeys(t, newFuzzDB(t, len(data)%2 == 0), msgs)
+	})
+}
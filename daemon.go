@@ -0,0 +1,506 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Own the workspace database and serve it to other chkmrk processes",
+	Long: `Run in the foreground holding the workspace database open, and serve it
+over a Unix socket next to it. While it runs, the TUI and CLI commands for
+the same workspace send their queries to it instead of opening the database,
+so it is the only writer and the TUI hears about changes straight away.`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+}
+
+// waitTimeout bounds how long a Wait call blocks, so clients notice a
+// daemon that went away.
+const waitTimeout = 30 * time.Second
+
+// daemonSocket is where the daemon for the database at path listens.
+func daemonSocket(path string) string {
+	return path + ".sock"
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	name, path, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	socket := daemonSocket(path)
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already serving %s on %s", name, socket)
+	}
+	// Nothing answered, so any socket file is left over from a daemon that
+	// didn't shut down cleanly.
+	os.Remove(socket)
+
+	db, closeDB, _, err := openCurrent(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	log.Printf("Serving %s on %s", name, socket)
+	if err := newDaemon(db).serve(l); !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// dialDaemon connects to the daemon serving the database at path, if one is
+// running.
+func dialDaemon(path string) (*sql.DB, bool) {
+	socket := daemonSocket(path)
+	conn, err := net.DialTimeout("unix", socket, 200*time.Millisecond)
+	if err != nil {
+		return nil, false
+	}
+	conn.Close()
+	return sql.OpenDB(daemonConnector{socket: socket}), true
+}
+
+// The JSON-RPC messages between clients and the daemon. Values are tagged
+// with their type because JSON alone can't tell an int64 from a float64 or
+// a string from a blob.
+type (
+	SQLValue struct {
+		Type  string  `json:"type"`
+		Int   int64   `json:"int,omitempty"`
+		Float float64 `json:"float,omitempty"`
+		Bool  bool    `json:"bool,omitempty"`
+		Text  string  `json:"text,omitempty"`
+		Blob  []byte  `json:"blob,omitempty"`
+	}
+	SQLQuery struct {
+		Query string     `json:"query"`
+		Args  []SQLValue `json:"args"`
+	}
+	SQLResult struct {
+		LastInsertID int64 `json:"last_insert_id"`
+		RowsAffected int64 `json:"rows_affected"`
+	}
+	SQLRows struct {
+		Columns []string     `json:"columns"`
+		Rows    [][]SQLValue `json:"rows"`
+	}
+)
+
+func toSQLValue(v interface{}) SQLValue {
+	switch v := v.(type) {
+	case int64:
+		return SQLValue{Type: "int", Int: v}
+	case float64:
+		return SQLValue{Type: "float", Float: v}
+	case bool:
+		return SQLValue{Type: "bool", Bool: v}
+	case string:
+		return SQLValue{Type: "text", Text: v}
+	case []byte:
+		return SQLValue{Type: "blob", Blob: v}
+	case time.Time:
+		return SQLValue{Type: "time", Text: v.Format(time.RFC3339Nano)}
+	default:
+		return SQLValue{Type: "null"}
+	}
+}
+
+func (v SQLValue) value() driver.Value {
+	switch v.Type {
+	case "int":
+		return v.Int
+	case "float":
+		return v.Float
+	case "bool":
+		return v.Bool
+	case "text":
+		return v.Text
+	case "blob":
+		return v.Blob
+	case "time":
+		t, _ := time.Parse(time.RFC3339Nano, v.Text)
+		return t
+	default:
+		return nil
+	}
+}
+
+func (q SQLQuery) args() []interface{} {
+	args := make([]interface{}, len(q.Args))
+	for i, arg := range q.Args {
+		args[i] = arg.value()
+	}
+	return args
+}
+
+// daemon serves a database to clients and tells them when it changes.
+type daemon struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	version int64
+	changed chan struct{}
+}
+
+func newDaemon(db *sql.DB) *daemon {
+	return &daemon{db: db, changed: make(chan struct{})}
+}
+
+func (d *daemon) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go d.serveConn(conn)
+	}
+}
+
+// serveConn serves one client connection. A transaction belongs to the
+// connection that began it and is rolled back if the client goes away.
+func (d *daemon) serveConn(conn net.Conn) {
+	s := &daemonSession{d: d}
+	srv := rpc.NewServer()
+	srv.RegisterName("DB", s)
+	srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx != nil {
+		s.tx.Rollback()
+	}
+}
+
+func (d *daemon) notify() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.version++
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// wait returns the version once it differs from since, or after timeout.
+func (d *daemon) wait(since int64, timeout time.Duration) int64 {
+	d.mu.Lock()
+	version, changed := d.version, d.changed
+	d.mu.Unlock()
+	if version != since {
+		return version
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(timeout):
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.version
+}
+
+type daemonSession struct {
+	d  *daemon
+	mu sync.Mutex
+	tx *sql.Tx
+}
+
+func (s *daemonSession) Exec(q SQLQuery, reply *SQLResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result sql.Result
+	var err error
+	if s.tx != nil {
+		result, err = s.tx.Exec(q.Query, q.args()...)
+	} else {
+		result, err = s.d.db.Exec(q.Query, q.args()...)
+	}
+	if err != nil {
+		return err
+	}
+	reply.LastInsertID, _ = result.LastInsertId()
+	reply.RowsAffected, _ = result.RowsAffected()
+	if s.tx == nil && reply.RowsAffected > 0 {
+		s.d.notify()
+	}
+	return nil
+}
+
+func (s *daemonSession) Query(q SQLQuery, reply *SQLRows) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows *sql.Rows
+	var err error
+	if s.tx != nil {
+		rows, err = s.tx.Query(q.Query, q.args()...)
+	} else {
+		rows, err = s.d.db.Query(q.Query, q.args()...)
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	reply.Columns, err = rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(reply.Columns))
+		ptrs := make([]interface{}, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		row := make([]SQLValue, len(values))
+		for i, v := range values {
+			row[i] = toSQLValue(v)
+		}
+		reply.Rows = append(reply.Rows, row)
+	}
+	return rows.Err()
+}
+
+func (s *daemonSession) Begin(_ int, _ *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx != nil {
+		return errors.New("transaction already open")
+	}
+	tx, err := s.d.db.Begin()
+	s.tx = tx
+	return err
+}
+
+func (s *daemonSession) Commit(_ int, _ *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx == nil {
+		return errors.New("no transaction open")
+	}
+	err := s.tx.Commit()
+	s.tx = nil
+	if err == nil {
+		s.d.notify()
+	}
+	return err
+}
+
+func (s *daemonSession) Rollback(_ int, _ *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx == nil {
+		return errors.New("no transaction open")
+	}
+	err := s.tx.Rollback()
+	s.tx = nil
+	return err
+}
+
+// Wait blocks until the database changes after version since, and replies
+// with the new version.
+func (s *daemonSession) Wait(since int64, version *int64) error {
+	*version = s.d.wait(since, waitTimeout)
+	return nil
+}
+
+// daemonConnector is a database/sql connector whose connections send every
+// statement to a daemon, so the storage functions work unchanged against
+// it.
+type daemonConnector struct {
+	socket string
+}
+
+func (c daemonConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return nil, err
+	}
+	return &daemonConn{client: jsonrpc.NewClient(conn)}, nil
+}
+
+func (c daemonConnector) Driver() driver.Driver {
+	return daemonDriver{socket: c.socket}
+}
+
+// daemonDriver opens connections to the daemon listening on the socket
+// named by the data source name. socket is the daemon a database opened
+// with dialDaemon talks to.
+type daemonDriver struct {
+	socket string
+}
+
+func (daemonDriver) Open(socket string) (driver.Conn, error) {
+	return daemonConnector{socket: socket}.Connect(context.Background())
+}
+
+type daemonConn struct {
+	client *rpc.Client
+}
+
+func toSQLQuery(query string, args []driver.Value) SQLQuery {
+	q := SQLQuery{Query: query, Args: make([]SQLValue, len(args))}
+	for i, arg := range args {
+		q.Args[i] = toSQLValue(arg)
+	}
+	return q
+}
+
+func (c *daemonConn) Prepare(query string) (driver.Stmt, error) {
+	return daemonStmt{c: c, query: query}, nil
+}
+
+func (c *daemonConn) Close() error {
+	return c.client.Close()
+}
+
+func (c *daemonConn) Begin() (driver.Tx, error) {
+	if err := c.client.Call("DB.Begin", 0, new(int)); err != nil {
+		return nil, err
+	}
+	return daemonTx{c}, nil
+}
+
+type daemonTx struct {
+	c *daemonConn
+}
+
+func (tx daemonTx) Commit() error {
+	return tx.c.client.Call("DB.Commit", 0, new(int))
+}
+
+func (tx daemonTx) Rollback() error {
+	return tx.c.client.Call("DB.Rollback", 0, new(int))
+}
+
+type daemonStmt struct {
+	c     *daemonConn
+	query string
+}
+
+func (s daemonStmt) Close() error  { return nil }
+func (s daemonStmt) NumInput() int { return -1 }
+
+func (s daemonStmt) Exec(args []driver.Value) (driver.Result, error) {
+	var result SQLResult
+	if err := s.c.client.Call("DB.Exec", toSQLQuery(s.query, args), &result); err != nil {
+		return nil, err
+	}
+	return daemonResult{result}, nil
+}
+
+func (s daemonStmt) Query(args []driver.Value) (driver.Rows, error) {
+	var rows SQLRows
+	if err := s.c.client.Call("DB.Query", toSQLQuery(s.query, args), &rows); err != nil {
+		return nil, err
+	}
+	return &daemonRows{rows: rows}, nil
+}
+
+type daemonResult struct {
+	result SQLResult
+}
+
+func (r daemonResult) LastInsertId() (int64, error) { return r.result.LastInsertID, nil }
+func (r daemonResult) RowsAffected() (int64, error) { return r.result.RowsAffected, nil }
+
+type daemonRows struct {
+	rows SQLRows
+	next int
+}
+
+func (r *daemonRows) Columns() []string { return r.rows.Columns }
+func (r *daemonRows) Close() error      { return nil }
+
+func (r *daemonRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows.Rows) {
+		return io.EOF
+	}
+	for i, v := range r.rows.Rows[r.next] {
+		dest[i] = v.value()
+	}
+	r.next++
+	return nil
+}
+
+// daemonWatcher long-polls the daemon's Wait in the background and
+// remembers whether anything changed since it was last asked.
+type daemonWatcher struct {
+	client *rpc.Client
+
+	mu      sync.Mutex
+	changed bool
+}
+
+func newDaemonWatcher(socket string) (*daemonWatcher, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	w := &daemonWatcher{client: jsonrpc.NewClient(conn)}
+
+	var version int64
+	if err := w.client.Call("DB.Wait", int64(-1), &version); err != nil {
+		w.client.Close()
+		return nil, err
+	}
+	go func() {
+		for {
+			since := version
+			if err := w.client.Call("DB.Wait", since, &version); err != nil {
+				return
+			}
+			if version != since {
+				w.mu.Lock()
+				w.changed = true
+				w.mu.Unlock()
+			}
+		}
+	}()
+	return w, nil
+}
+
+func (w *daemonWatcher) take() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changed := w.changed
+	w.changed = false
+	return changed
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	db, closeDB, err := openSQLite(filepath.Join(dir, "checklist.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+
+	socket := daemonSocket(filepath.Join(dir, "checklist.db"))
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go newDaemon(db).serve(l)
+
+	client, ok := dialDaemon(filepath.Join(dir, "checklist.db"))
+	if !ok {
+		t.Fatalf("dialDaemon() found no daemon")
+	}
+	defer client.Close()
+
+	w, err := newChangeWatcher(client)
+	if err != nil || w.daemon == nil {
+		t.Fatalf("newChangeWatcher() = %v, %v; expected a daemon watcher", w, err)
+	}
+	defer w.Close()
+
+	addChecklist(client, "Release")
+	addItem(client, "Tag", false, 1)
+	addItem(client, "Test", false, 1)
+	if err := updateItemsCompleted(client, []int{1, 2}, true); err != nil {
+		t.Fatalf("updateItemsCompleted() through the daemon error = %v", err)
+	}
+	updateItemNote(client, 2, "run `go test ./...`")
+
+	// A failed transaction rolls back on the daemon.
+	withTx(client, func(tx *sql.Tx) error {
+		tx.Exec(`DELETE FROM items`)
+		return errors.New("abort")
+	})
+
+	if err := addItem(client, "Orphan", false, 9); err == nil {
+		t.Errorf("addItem() to a missing checklist through the daemon succeeded")
+	}
+
+	items, err := getItemsByChecklistId(db, 1)
+	if err != nil || len(items) != 2 || !items[0].Completed || !items[1].Completed {
+		t.Fatalf("items in the daemon's database = %v, %v; expected two completed items", items, err)
+	}
+	item, err := getItemById(client, 2)
+	if err != nil || item.Note != "run `go test ./...`" || item.ChecklistID != 1 {
+		t.Errorf("getItemById() through the daemon = %+v, %v", item, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for changed, _ := w.changed(); !changed; changed, _ = w.changed() {
+		if time.Now().After(deadline) {
+			t.Fatalf("watcher heard of no change from the daemon")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// lockIcon marks an item that waits on unfinished prerequisites.
+const lockIcon = "🔒"
+
+// blockedColumn is the SQL for whether the row of items being selected has
+// a prerequisite that is neither completed nor in the trash. depends_on
+// holds the prerequisites' UUIDs so it syncs like any other field.
+const blockedColumn = `EXISTS (
+	SELECT 1 FROM json_each(items.depends_on) d JOIN items p ON p.uuid = d.value
+	WHERE p.completed = 0 AND p.deleted_at IS NULL)`
+
+var depsClearFlag bool
+
+var depsCmd = &cobra.Command{
+	Use:   "deps <item-id> [<prerequisite-id>...]",
+	Short: "Show or set the items an item depends on",
+	Long: `With only an item, print the items it depends on. With prerequisites,
+make the item depend on exactly those, which must be in the same checklist.
+An item can't be checked until its prerequisites are.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runDeps,
+}
+
+func init() {
+	depsCmd.Flags().BoolVar(&depsClearFlag, "clear", false, "remove every prerequisite of the item")
+}
+
+// blockedError is returned when checking an item whose prerequisites
+// aren't done.
+type blockedError struct {
+	Item Item
+	By   []Item
+}
+
+func (e *blockedError) Error() string {
+	titles := make([]string, len(e.By))
+	for i, item := range e.By {
+		titles[i] = fmt.Sprintf("%q", item.Title)
+	}
+	return fmt.Sprintf("%q is blocked by %s", e.Item.Title, strings.Join(titles, ", "))
+}
+
+// getDependencies returns the prerequisites of an item, in checklist order.
+// Prerequisites in the trash don't count.
+func getDependencies(db *sql.DB, item_id int) ([]Item, error) {
+	query := `
+	SELECT p.id, p.title, p.completed, p.note
+	FROM items i, json_each(i.depends_on) d JOIN items p ON p.uuid = d.value
+	WHERE i.id = ? AND p.deleted_at IS NULL
+	ORDER BY p.position, p.id`
+	rows, err := db.Query(query, item_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.ID, &item.Title, &item.Completed, &item.Note); err != nil {
+			return nil, err
+		}
+		if err := unsealItem(db, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// setDependencies makes an item depend on exactly depends_on, which must be
+// other items of its checklist and must not lead back to it.
+func setDependencies(db *sql.DB, item_id int, depends_on []int) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		var checklistID int
+		if err := tx.QueryRow(`SELECT checklist_id FROM items WHERE id = ? AND deleted_at IS NULL`, item_id).Scan(&checklistID); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("no item with id %d", item_id)
+			}
+			return err
+		}
+
+		rows, err := tx.Query(`SELECT id, uuid, depends_on FROM items WHERE checklist_id = ? AND deleted_at IS NULL`, checklistID)
+		if err != nil {
+			return err
+		}
+		uuids := map[int]string{}
+		ids := map[string]int{}
+		stored := map[int]string{}
+		for rows.Next() {
+			var id int
+			var uuid, deps string
+			if err := rows.Scan(&id, &uuid, &deps); err != nil {
+				rows.Close()
+				return err
+			}
+			uuids[id], ids[uuid], stored[id] = uuid, id, deps
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		graph := map[int][]int{}
+		for id, deps := range stored {
+			var prereqs []string
+			json.Unmarshal([]byte(deps), &prereqs)
+			for _, uuid := range prereqs {
+				if prereq, ok := ids[uuid]; ok {
+					graph[id] = append(graph[id], prereq)
+				}
+			}
+		}
+
+		prereqs := []string{}
+		graph[item_id] = nil
+		for _, id := range depends_on {
+			uuid, ok := uuids[id]
+			switch {
+			case id == item_id:
+				return errors.New("an item can't depend on itself")
+			case !ok:
+				return fmt.Errorf("item %d isn't in the same checklist", id)
+			}
+			prereqs = append(prereqs, uuid)
+			graph[item_id] = append(graph[item_id], id)
+		}
+		if reaches(graph, item_id, item_id) {
+			return errors.New("that would make items depend on each other in a loop")
+		}
+
+		value, err := json.Marshal(prereqs)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE items SET depends_on = ? WHERE id = ?`, string(value), item_id)
+		return err
+	})
+}
+
+// reaches reports whether to can be reached from the prerequisites of from.
+func reaches(graph map[int][]int, from, to int) bool {
+	seen := map[int]bool{}
+	stack := append([]int(nil), graph[from]...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		if !seen[id] {
+			seen[id] = true
+			stack = append(stack, graph[id]...)
+		}
+	}
+	return false
+}
+
+// checkBlocked returns a *blockedError if any of the items can't be checked
+// yet. Prerequisites among ids don't block, since they are checked together.
+func checkBlocked(db *sql.DB, ids []int) error {
+	checking := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		checking[id] = true
+	}
+
+	for _, id := range ids {
+		deps, err := getDependencies(db, id)
+		if err != nil {
+			return err
+		}
+		var blockers []Item
+		for _, dep := range deps {
+			if !dep.Completed && !checking[dep.ID] {
+				blockers = append(blockers, dep)
+			}
+		}
+		if len(blockers) > 0 {
+			item, err := getItemById(db, id)
+			if err != nil {
+				return err
+			}
+			return &blockedError{Item: item, By: blockers}
+		}
+	}
+	return nil
+}
+
+// nextActionable returns the index of the first item after from, wrapping
+// around, that is neither done nor blocked, or -1 if there is none.
+func nextActionable(items []Item, from int) int {
+	for step := 1; step <= len(items); step++ {
+		i := (from + step) % len(items)
+		if !items[i].Completed && !items[i].Blocked {
+			return i
+		}
+	}
+	return -1
+}
+
+// openDependencyPicker lets the user add or remove prerequisites of the
+// item under the cursor, one pick at a time.
+func (m *model) openDependencyPicker() {
+	item, ok := m.cursorItem()
+	if !ok {
+		return
+	}
+	deps, err := getDependencies(m.db, item.ID)
+	if err != nil {
+		m.err = err
+		return
+	}
+	current := make(map[int]bool, len(deps))
+	for _, dep := range deps {
+		current[dep.ID] = true
+	}
+
+	var others []Item
+	var options []string
+	for _, other := range m.items {
+		if other.ID == item.ID {
+			continue
+		}
+		mark := " "
+		if current[other.ID] {
+			mark = "x"
+		}
+		others = append(others, other)
+		options = append(options, fmt.Sprintf("[%s] %s", mark, other.Title))
+	}
+
+	m.openPicker(fmt.Sprintf("%s depends on:", item.Title), options, func(m *model, i int) {
+		current[others[i].ID] = !current[others[i].ID]
+		var ids []int
+		for _, other := range others {
+			if current[other.ID] {
+				ids = append(ids, other.ID)
+			}
+		}
+		if err := setDependencies(m.db, item.ID, ids); err != nil {
+			m.err = err
+		}
+		m.reloadItems()
+	})
+}
+
+func runDeps(cmd *cobra.Command, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if depsClearFlag || len(ids) > 1 {
+		if err := setDependencies(db, ids[0], ids[1:]); err != nil {
+			return err
+		}
+	}
+
+	deps, err := getDependencies(db, ids[0])
+	if err != nil {
+		return err
+	}
+	if len(deps) == 0 {
+		fmt.Printf("Item %d depends on nothing\n", ids[0])
+	}
+	for _, dep := range deps {
+		RenderItemInBuffer(os.Stdout, dep)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDependencies(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addChecklist(db, "Other")
+	for _, title := range []string{"Test", "Tag", "Push", "Announce"} {
+		addItem(db, title, false, 1)
+	}
+	addItem(db, "Elsewhere", false, 2)
+
+	if err := setDependencies(db, 2, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setDependencies(db, 3, []int{2}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		item    int
+		deps    []int
+		wantErr bool
+	}{
+		{4, []int{3, 1}, false},
+		{4, nil, false},
+		{1, []int{1}, true},
+		{1, []int{5}, true},
+		{1, []int{3}, true},
+		{9, []int{1}, true},
+	}
+	for index, test := range tests {
+		if err := setDependencies(db, test.item, test.deps); (err != nil) != test.wantErr {
+			t.Errorf("Test number %d -> setDependencies(%d, %v) error = %v; wantErr %v", index, test.item, test.deps, err, test.wantErr)
+		}
+	}
+
+	items, _ := getItemsByChecklistId(db, 1)
+	blocked := []bool{false, true, true, false}
+	for index, item := range items {
+		if item.Blocked != blocked[index] {
+			t.Errorf("Test number %d -> %s blocked = %v; expected %v", index, item.Title, item.Blocked, blocked[index])
+		}
+	}
+
+	var blockedErr *blockedError
+	if err := checkBlocked(db, []int{3}); !errors.As(err, &blockedErr) || blockedErr.By[0].Title != "Tag" {
+		t.Errorf("checkBlocked(Push) = %v; expected it blocked by Tag", err)
+	}
+	if err := checkBlocked(db, []int{1, 2, 3}); err != nil {
+		t.Errorf("checkBlocked(Test, Tag, Push) = %v; expected prerequisites checked together to pass", err)
+	}
+
+	// Trashing a prerequisite unblocks what waited on it.
+	deleteItem(db, 1)
+	if item, _ := getItemById(db, 2); item.Blocked {
+		t.Errorf("Tag still blocked after its prerequisite went to the trash")
+	}
+}
+
+func TestMoveDropsDependencies(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addChecklist(db, "Later")
+	for _, title := range []string{"Test", "Tag", "Push"} {
+		addItem(db, title, false, 1)
+	}
+	setDependencies(db, 2, []int{1})
+	setDependencies(db, 3, []int{1, 2})
+
+	if err := moveItems(db, []int{2}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		item     int
+		expected []string
+	}{
+		{2, nil},
+		{3, []string{"Test"}},
+	}
+	for index, test := range tests {
+		deps, err := getDependencies(db, test.item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, dep := range deps {
+			titles = append(titles, dep.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("Test number %d -> item %d depends on %v; expected %v", index, test.item, titles, test.expected)
+		}
+	}
+}
+
+func TestNextActionable(t *testing.T) {
+	items := []Item{{Completed: true}, {Blocked: true}, {}, {Completed: true}, {}}
+
+	tests := []struct {
+		from     int
+		expected int
+	}{
+		{0, 2},
+		{2, 4},
+		{4, 2},
+	}
+	for index, test := range tests {
+		if actual := nextActionable(items, test.from); actual != test.expected {
+			t.Errorf("Test number %d -> nextActionable(%d) = %d; expected %d", index, test.from, actual, test.expected)
+		}
+	}
+	if actual := nextActionable([]Item{{Completed: true}, {Blocked: true}}, 0); actual != -1 {
+		t.Errorf("nextActionable() = %d with nothing to do; expected -1", actual)
+	}
+}
+
+func TestBlockedToggle(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addItem(db, "Test", false, 1)
+	addItem(db, "Tag", false, 1)
+	setDependencies(db, 2, []int{1})
+
+	press := func(m tea.Model, msg tea.KeyMsg) model {
+		next, _ := m.Update(msg)
+		return next.(model)
+	}
+	m := initialModel(db, defaultKeyMap())
+	m.openChecklist()
+	m.moveCursor(1)
+
+	m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.items[1].Completed || !strings.Contains(ChecklistDetailView(m), "blocked by") {
+		t.Errorf("toggling a blocked item checked it or gave no warning:\n%s", ChecklistDetailView(m))
+	}
+	if !strings.Contains(ChecklistDetailView(m), lockIcon+" Tag") {
+		t.Errorf("blocked item isn't shown locked:\n%s", ChecklistDetailView(m))
+	}
+
+	m = press(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if m.cursor != 0 {
+		t.Errorf("next actionable moved the cursor to %d; expected 0", m.cursor)
+	}
+
+	srv := httptest.NewServer(newAPI(db))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/items/2/toggle", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("POST /items/2/toggle = %d; expected %d", resp.StatusCode, http.StatusConflict)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+type keyMap struct {
+	Up         key.Binding
+	Down       key.Binding
+	PageUp     key.Binding
+	PageDown   key.Binding
+	Top        key.Binding
+	Bottom     key.Binding
+	Open       key.Binding
+	Back       key.Binding
+	Focus      key.Binding
+	New        key.Binding
+	Delete     key.Binding
+	Toggle     key.Binding
+	Visual     key.Binding
+	SelectUp   key.Binding
+	SelectDown key.Binding
+	Move       key.Binding
+	Copy       key.Binding
+	Note       key.Binding
+	NoteEditor key.Binding
+	EditList   key.Binding
+	Workspace  key.Binding
+	Next       key.Binding
+	Depends    key.Binding
+	Run        key.Binding
+	Command    key.Binding
+	Retag      key.Binding
+	Trash      key.Binding
+	Restore    key.Binding
+	Help       key.Binding
+	Quit       key.Binding
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Help, k.Quit}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom},
+		{k.Open, k.Back, k.Focus},
+		{k.New, k.Delete, k.Toggle, k.Move, k.Copy, k.Retag},
+		{k.Trash, k.Restore},
+		{k.Visual, k.SelectUp, k.SelectDown},
+		{k.Next, k.Depends},
+		{k.Run, k.Command},
+		{k.Note, k.NoteEditor, k.EditList},
+		{k.Workspace, k.Help, k.Quit},
+	}
+}
+
+// bindings maps the action names used in the config file to their bindings.
+func (k *keyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":         &k.Up,
+		"down":       &k.Down,
+		"pageup":     &k.PageUp,
+		"pagedown":   &k.PageDown,
+		"top":        &k.Top,
+		"bottom":     &k.Bottom,
+		"open":       &k.Open,
+		"back":       &k.Back,
+		"focus":      &k.Focus,
+		"new":        &k.New,
+		"delete":     &k.Delete,
+		"toggle":     &k.Toggle,
+		"visual":     &k.Visual,
+		"selectup":   &k.SelectUp,
+		"selectdown": &k.SelectDown,
+		"move":       &k.Move,
+		"copy":       &k.Copy,
+		"note":       &k.Note,
+		"noteeditor": &k.NoteEditor,
+		"editlist":   &k.EditList,
+		"workspace":  &k.Workspace,
+		"next":       &k.Next,
+		"depends":    &k.Depends,
+		"run":        &k.Run,
+		"command":    &k.Command,
+		"retag":      &k.Retag,
+		"trash":      &k.Trash,
+		"restore":    &k.Restore,
+		"help":       &k.Help,
+		"quit":       &k.Quit,
+	}
+}
+
+func binding(desc string, keys ...string) key.Binding {
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		if k == " " {
+			k = "space"
+		}
+		labels[i] = k
+	}
+	return key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(strings.Join(labels, "/"), desc),
+	)
+}
+
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Up:         binding("up", "up", "k"),
+		Down:       binding("down", "down", "j"),
+		PageUp:     binding("page up", "pgup", "ctrl+u"),
+		PageDown:   binding("page down", "pgdown", "ctrl+d"),
+		Top:        binding("go to top", "home", "g"),
+		Bottom:     binding("go to bottom", "end", "G"),
+		Open:       binding("open list", "right", "l"),
+		Back:       binding("back", "left", "h"),
+		Focus:      binding("switch pane", "tab"),
+		New:        binding("new", "n"),
+		Delete:     binding("delete", "x"),
+		Toggle:     binding("check/uncheck", "enter", " "),
+		Visual:     binding("select range", "V"),
+		SelectUp:   binding("extend selection up", "shift+up"),
+		SelectDown: binding("extend selection down", "shift+down"),
+		Move:       binding("move to list", "m"),
+		Copy:       binding("copy to list", "c"),
+		Note:       binding("edit note", "e"),
+		NoteEditor: binding("edit note in $EDITOR", "E"),
+		EditList:   binding("edit list in $EDITOR", "ctrl+e"),
+		Workspace:  binding("switch workspace", "W"),
+		Next:       binding("next actionable item", "a"),
+		Depends:    binding("edit dependencies", "D"),
+		Run:        binding("run command", "r"),
+		Command:    binding("edit command", "!"),
+		Retag:      binding("retag", "t"),
+		Trash:      binding("open trash", "T"),
+		Restore:    binding("restore from trash", "u"),
+		Help:       binding("toggle help", "?"),
+		Quit:       binding("quit", "q", "ctrl+c"),
+	}
+}
+
+func vimKeyMap() keyMap {
+	return keyMap{
+		Up:         binding("up", "k"),
+		Down:       binding("down", "j"),
+		PageUp:     binding("page up", "ctrl+b", "ctrl+u"),
+		PageDown:   binding("page down", "ctrl+f", "ctrl+d"),
+		Top:        binding("go to top", "g"),
+		Bottom:     binding("go to bottom", "G"),
+		Open:       binding("open list", "l"),
+		Back:       binding("back", "h"),
+		Focus:      binding("switch pane", "tab"),
+		New:        binding("new", "o", "n"),
+		Delete:     binding("delete", "x"),
+		Toggle:     binding("check/uncheck", " ", "enter"),
+		Visual:     binding("select range", "V"),
+		SelectUp:   binding("extend selection up", "K"),
+		SelectDown: binding("extend selection down", "J"),
+		Move:       binding("move to list", "m"),
+		Copy:       binding("copy to list", "c", "y"),
+		Note:       binding("edit note", "e"),
+		NoteEditor: binding("edit note in $EDITOR", "E"),
+		EditList:   binding("edit list in $EDITOR", "ctrl+e"),
+		Workspace:  binding("switch workspace", "W"),
+		Next:       binding("next actionable item", "a"),
+		Depends:    binding("edit dependencies", "D"),
+		Run:        binding("run command", "r"),
+		Command:    binding("edit command", "!"),
+		Retag:      binding("retag", "t"),
+		Trash:      binding("open trash", "T"),
+		Restore:    binding("restore from trash", "u"),
+		Help:       binding("toggle help", "?"),
+		Quit:       binding("quit", "q", "ctrl+c"),
+	}
+}
+
+func emacsKeyMap() keyMap {
+	return keyMap{
+		Up:         binding("up", "ctrl+p", "up"),
+		Down:       binding("down", "ctrl+n", "down"),
+		PageUp:     binding("page up", "alt+v", "pgup"),
+		PageDown:   binding("page down", "ctrl+v", "pgdown"),
+		Top:        binding("go to top", "alt+<", "home"),
+		Bottom:     binding("go to bottom", "alt+>", "end"),
+		Open:       binding("open list", "ctrl+f", "right"),
+		Back:       binding("back", "ctrl+b", "left"),
+		Focus:      binding("switch pane", "tab"),
+		New:        binding("new", "ctrl+o"),
+		Delete:     binding("delete", "ctrl+d"),
+		Toggle:     binding("check/uncheck", "ctrl+t", "enter"),
+		Visual:     binding("select range", "ctrl+@"),
+		SelectUp:   binding("extend selection up", "shift+up"),
+		SelectDown: binding("extend selection down", "shift+down"),
+		Move:       binding("move to list", "alt+m"),
+		Copy:       binding("copy to list", "alt+w"),
+		Note:       binding("edit note", "alt+n"),
+		NoteEditor: binding("edit note in $EDITOR", "alt+e"),
+		EditList:   binding("edit list in $EDITOR", "alt+l"),
+		Workspace:  binding("switch workspace", "alt+p"),
+		Next:       binding("next actionable item", "alt+a"),
+		Depends:    binding("edit dependencies", "alt+d"),
+		Run:        binding("run command", "alt+r"),
+		Command:    binding("edit command", "alt+!"),
+		Retag:      binding("retag", "alt+g"),
+		Trash:      binding("open trash", "alt+t"),
+		Restore:    binding("restore from trash", "alt+u"),
+		Help:       binding("toggle help", "ctrl+h", "?"),
+		Quit:       binding("quit", "ctrl+g", "ctrl+c"),
+	}
+}
+
+// newKeyMap builds the keymap for the preset named in cfg and applies any
+// per-action overrides on top of it.
+func newKeyMap(cfg Config) (keyMap, error) {
+	var keys keyMap
+	switch cfg.Keymap {
+	case "", "default":
+		keys = defaultKeyMap()
+	case "vim":
+		keys = vimKeyMap()
+	case "emacs":
+		keys = emacsKeyMap()
+	default:
+		return defaultKeyMap(), fmt.Errorf("unknown keymap %q", cfg.Keymap)
+	}
+
+	bindings := keys.bindings()
+	for name, override := range cfg.Keys {
+		b, ok := bindings[name]
+		if !ok {
+			return keys, fmt.Errorf("unknown key action %q", name)
+		}
+		*b = binding(b.Help().Desc, override...)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	tests := []struct {
+		cfg      Config
+		action   string
+		expected []string
+		wantErr  bool
+	}{
+		{Config{}, "open", []string{"right", "l"}, false},
+		{Config{Keymap: "vim"}, "new", []string{"o", "n"}, false},
+		{Config{Keymap: "emacs"}, "up", []string{"ctrl+p", "up"}, false},
+		{Config{Keys: map[string][]string{"delete": {"d"}}}, "delete", []string{"d"}, false},
+		{Config{Keymap: "emacs", Keys: map[string][]string{"quit": {"ctrl+x"}}}, "quit", []string{"ctrl+x"}, false},
+		{Config{Keymap: "nano"}, "up", []string{"up", "k"}, true},
+		{Config{Keys: map[string][]string{"jump": {"g"}}}, "up", []string{"up", "k"}, true},
+	}
+
+	for index, test := range tests {
+		keys, err := newKeyMap(test.cfg)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Test number %d -> newKeyMap(%v) error = %v; wantErr %v", index, test.cfg, err, test.wantErr)
+		}
+
+		actual := keys.bindings()[test.action].Keys()
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test number %d -> newKeyMap(%v).%s = %v; expected %v", index, test.cfg, test.action, actual, test.expected)
+		}
+	}
+}
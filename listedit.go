@@ -0,0 +1,275 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type listEditedMsg struct {
+	checklistID int
+	items       []Item
+	path        string
+	err         error
+}
+
+// editLine is one checklist line read back from the editor. ID is zero for
+// lines the user added.
+type editLine struct {
+	ID        int
+	Title     string
+	Completed bool
+	Note      string
+}
+
+type checklistDiff struct {
+	Added     []Item
+	Removed   []int
+	Updated   []Item
+	Unchanged []Item
+}
+
+func (d checklistDiff) String() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed", len(d.Added), len(d.Removed), len(d.Updated))
+}
+
+var editLinePattern = regexp.MustCompile(`^\s*[-*]\s+\[([ xX])\]\s?(.*?)\s*(?:<!--\s*id:(\d+)\s*-->)?\s*$`)
+
+// formatChecklist writes items as markdown task lines, each tagged with its
+// ID in a trailing comment so edits can be matched back to the database.
+func formatChecklist(title string, items []Item) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	b.WriteString("<!-- Edit, add, remove or reorder lines. Keep the id comments to preserve notes. -->\n\n")
+	for _, item := range items {
+		checked := " "
+		if item.Completed {
+			checked = "x"
+		}
+		fmt.Fprintf(&b, "- [%s] %s <!-- id:%d -->\n", checked, item.Title, item.ID)
+	}
+	return b.String()
+}
+
+// parseChecklist reads task lines back, ignoring anything that isn't one.
+// Indented lines right below a task are read as that task's note.
+func parseChecklist(text string) []editLine {
+	blocks := parseTaskBlocks(strings.Split(text, "\n"))
+	lines := make([]editLine, len(blocks))
+	for i, block := range blocks {
+		lines[i] = block.line
+	}
+	return lines
+}
+
+// taskBlock is a task line and the note lines below it: lines [start, end)
+// of the text it was parsed from.
+type taskBlock struct {
+	start, end int
+	line       editLine
+}
+
+// parseTaskBlocks finds the tasks in lines and where each one is, so a
+// file can be rewritten task by task without touching anything else.
+func parseTaskBlocks(lines []string) []taskBlock {
+	var blocks []taskBlock
+	next := -1 // the line that would continue the last task's note
+	for i, line := range lines {
+		if n := len(blocks); n > 0 && i == next && isNoteLine(line, blocks[n-1].line.Note != "") {
+			note := strings.TrimPrefix(line, "\t")
+			if note == line {
+				note = strings.TrimPrefix(line, "  ")
+			}
+			blocks[n-1].line.Note += note + "\n"
+			if line != "" {
+				blocks[n-1].end = i + 1
+			}
+			next = i + 1
+			continue
+		}
+
+		match := editLinePattern.FindStringSubmatch(line)
+		if match == nil || match[2] == "" {
+			continue
+		}
+		id, _ := strconv.Atoi(match[3])
+		blocks = append(blocks, taskBlock{start: i, end: i + 1, line: editLine{
+			ID:        id,
+			Title:     match[2],
+			Completed: match[1] != " ",
+		}})
+		next = i + 1
+	}
+
+	for i := range blocks {
+		blocks[i].line.Note = strings.TrimRight(blocks[i].line.Note, "\n")
+	}
+	return blocks
+}
+
+// isNoteLine reports whether line continues the note of the task above it:
+// it is indented, or blank in the middle of a note.
+func isNoteLine(line string, inNote bool) bool {
+	if line == "" {
+		return inNote
+	}
+	return strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+}
+
+// diffChecklist compares the stored items with the edited lines. Each line's
+// position becomes the item's new position. Lines whose ID comment was lost
+// are matched to a removed item with the same title before being treated as
+// new.
+func diffChecklist(items []Item, lines []editLine) checklistDiff {
+	stored := make(map[int]Item, len(items))
+	for i, item := range items {
+		item.Index = i + 1
+		stored[item.ID] = item
+	}
+
+	kept := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		kept[line.ID] = true
+	}
+
+	unclaimed := map[string][]int{}
+	for _, item := range items {
+		if !kept[item.ID] {
+			unclaimed[item.Title] = append(unclaimed[item.Title], item.ID)
+		}
+	}
+
+	var diff checklistDiff
+	seen := make(map[int]bool, len(lines))
+	for i, line := range lines {
+		id := line.ID
+		if _, ok := stored[id]; !ok || seen[id] {
+			id = 0
+			if ids := unclaimed[line.Title]; len(ids) > 0 {
+				id = ids[0]
+				unclaimed[line.Title] = ids[1:]
+			}
+		}
+
+		next := Item{ID: id, Index: i + 1, Title: line.Title, Completed: line.Completed}
+		if id == 0 {
+			diff.Added = append(diff.Added, next)
+			continue
+		}
+
+		seen[id] = true
+		prev := stored[id]
+		if prev.Title != next.Title || prev.Completed != next.Completed || prev.Index != next.Index {
+			diff.Updated = append(diff.Updated, next)
+		} else {
+			diff.Unchanged = append(diff.Unchanged, next)
+		}
+	}
+
+	for _, item := range items {
+		if !seen[item.ID] {
+			diff.Removed = append(diff.Removed, item.ID)
+		}
+	}
+
+	return diff
+}
+
+// applyChecklistDiff writes the diff to the checklist in one transaction.
+// Item.Index holds the new position; unchanged items are renumbered too so
+// positions stay contiguous. Removed items go to the trash.
+func applyChecklistDiff(db *sql.DB, checklist_id int, diff checklistDiff) error {
+	now := time.Now().Unix()
+	return withTx(db, func(tx *sql.Tx) error {
+		for _, id := range diff.Removed {
+			if _, err := tx.Exec(`UPDATE items SET deleted_at = ? WHERE id = ?`, now, id); err != nil {
+				return err
+			}
+		}
+		for _, item := range diff.Updated {
+			title, err := seal(db, item.Title)
+			if err != nil {
+				return err
+			}
+			query := `UPDATE items SET title = ?, completed = ?, position = ? WHERE id = ?`
+			if _, err := tx.Exec(query, title, item.Completed, item.Index, item.ID); err != nil {
+				return err
+			}
+		}
+		for _, item := range diff.Unchanged {
+			if _, err := tx.Exec(`UPDATE items SET position = ? WHERE id = ?`, item.Index, item.ID); err != nil {
+				return err
+			}
+		}
+		for _, item := range diff.Added {
+			title, err := seal(db, item.Title)
+			if err != nil {
+				return err
+			}
+			query := `INSERT INTO items (title, completed, checklist_id, position) VALUES (?, ?, ?, ?)`
+			if _, err := tx.Exec(query, title, item.Completed, checklist_id, item.Index); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeChecklistFile writes the checklist to a temp file for editing.
+func writeChecklistFile(title string, items []Item) (string, error) {
+	f, err := os.CreateTemp("", "chkmrk-list-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(formatChecklist(title, items))
+	return f.Name(), err
+}
+
+// applyChecklistFile reads the edited file back and applies the changes
+// against the items the file was written from.
+func applyChecklistFile(db *sql.DB, checklist_id int, items []Item, path string) (checklistDiff, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return checklistDiff{}, err
+	}
+
+	diff := diffChecklist(items, parseChecklist(string(text)))
+	return diff, applyChecklistDiff(db, checklist_id, diff)
+}
+
+// openListInEditor hands the active checklist to $EDITOR as markdown.
+func (m model) openListInEditor() tea.Cmd {
+	items := m.items
+	path, err := writeChecklistFile(m.activeListTitle, items)
+	if err != nil {
+		return func() tea.Msg { return errMsg(err) }
+	}
+
+	id := m.activeList
+	return tea.ExecProcess(editorCmd(path), func(err error) tea.Msg {
+		return listEditedMsg{checklistID: id, items: items, path: path, err: err}
+	})
+}
+
+func ListEditedAction(m model, msg listEditedMsg) (tea.Model, tea.Cmd) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+
+	if _, err := applyChecklistFile(m.db, msg.checklistID, msg.items, msg.path); err != nil {
+		m.err = err
+	}
+	m.reloadItems()
+	m.moveCursor(0)
+	return m, nil
+}
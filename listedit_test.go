@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChecklist(t *testing.T) {
+	text := `# Release
+
+<!-- a comment -->
+- [ ] Tag release <!-- id:3 -->
+- [x] Run tests <!-- id:12 -->
+* [X] Announce
+- [ ]
+not a task
+`
+	expected := []editLine{
+		{ID: 3, Title: "Tag release"},
+		{ID: 12, Title: "Run tests", Completed: true},
+		{Title: "Announce", Completed: true},
+	}
+
+	actual := parseChecklist(text)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("parseChecklist() = %v; expected %v", actual, expected)
+	}
+
+	items := []Item{{ID: 1, Title: "One", Completed: true}, {ID: 2, Title: "Two"}}
+	roundTrip := parseChecklist(formatChecklist("List", items))
+	expected = []editLine{{ID: 1, Title: "One", Completed: true}, {ID: 2, Title: "Two"}}
+	if !reflect.DeepEqual(roundTrip, expected) {
+		t.Errorf("parseChecklist(formatChecklist()) = %v; expected %v", roundTrip, expected)
+	}
+}
+
+func TestDiffChecklist(t *testing.T) {
+	items := []Item{
+		{ID: 1, Title: "One"},
+		{ID: 2, Title: "Two"},
+		{ID: 3, Title: "Three"},
+		{ID: 4, Title: "Four"},
+	}
+
+	tests := []struct {
+		lines    []editLine
+		expected checklistDiff
+	}{
+		{
+			// Nothing changed.
+			[]editLine{{ID: 1, Title: "One"}, {ID: 2, Title: "Two"}, {ID: 3, Title: "Three"}, {ID: 4, Title: "Four"}},
+			checklistDiff{Unchanged: []Item{
+				{ID: 1, Index: 1, Title: "One"},
+				{ID: 2, Index: 2, Title: "Two"},
+				{ID: 3, Index: 3, Title: "Three"},
+				{ID: 4, Index: 4, Title: "Four"},
+			}},
+		},
+		{
+			// Rename, toggle, remove and add.
+			[]editLine{{ID: 1, Title: "Uno"}, {ID: 2, Title: "Two", Completed: true}, {ID: 4, Title: "Four"}, {Title: "Five"}},
+			checklistDiff{
+				Added:   []Item{{Index: 4, Title: "Five"}},
+				Removed: []int{3},
+				Updated: []Item{
+					{ID: 1, Index: 1, Title: "Uno"},
+					{ID: 2, Index: 2, Title: "Two", Completed: true},
+					{ID: 4, Index: 3, Title: "Four"},
+				},
+			},
+		},
+		{
+			// Reorder, with one ID comment lost and a duplicated ID.
+			[]editLine{{ID: 4, Title: "Four"}, {Title: "Three"}, {ID: 2, Title: "Two"}, {ID: 1, Title: "One"}, {ID: 1, Title: "One again"}},
+			checklistDiff{
+				Added: []Item{{Index: 5, Title: "One again"}},
+				Updated: []Item{
+					{ID: 4, Index: 1, Title: "Four"},
+					{ID: 3, Index: 2, Title: "Three"},
+					{ID: 2, Index: 3, Title: "Two"},
+					{ID: 1, Index: 4, Title: "One"},
+				},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		actual := diffChecklist(items, test.lines)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test number %d -> diffChecklist() = %#v; expected %#v", index, actual, test.expected)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strings"
+	"time"
 	// "flag"
 	"fmt"
 	"io"
@@ -17,8 +19,13 @@ import (
 	"ChkMrk/cmd"
 	"slices"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	textinput "github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
 )
 
 type (
@@ -32,13 +39,7 @@ const (
 	Checklists
 	ChecklistDetail
 	Templates
-)
-
-var (
-	addItemFlag    string
-	checkItemFlag  int
-	removeItemFlag int
-	listItemsFlag  bool
+	Trash
 )
 
 type model struct {
@@ -54,13 +55,45 @@ type model struct {
 	layout          Layout
 	activeList      int
 	activeListTitle string
+	keys            keyMap
+	help            help.Model
+	showHelp        bool
+	width           int
+	height          int
+	offset          int
+	listCursor      int
+	preview         []Item
+	visual          bool
+	anchor          int
+	picking         bool
+	pickTitle       string
+	pickOptions     []string
+	pickCursor      int
+	onPick          func(m *model, i int)
+	editingNote     bool
+	noteInput       textarea.Model
+	noteItemID      int
+	noteStyle       string
+	noteRenderers   map[int]*glamour.TermRenderer
+	cfg             Config
+	workspace       string
+	closeDB         func() error
+	watcher         *changeWatcher
+	trash           []trashEntry
+	confirming      bool
+	confirmQuestion string
+	confirmPreview  []string
+	onConfirm       func(m *model) tea.Cmd
+	editingCommand  bool
+	editingTags     bool
+	run             *commandRun
 }
 
-func initialModel(db *sql.DB) model {
+func initialModel(db *sql.DB, keys keyMap) model {
 	items, _ := getItems(db)
 
 	checklists, _ := getChecklists(db)
-	choices := make([]string, len(items))
+	choices := make([]string, len(checklists))
 	for i, list := range checklists {
 		choices[i] = list.Title
 	}
@@ -91,11 +124,15 @@ func initialModel(db *sql.DB) model {
 		layout:          currentLayout,
 		activeList:      -1,
 		activeListTitle: "",
+		keys:            keys,
+		help:            help.New(),
+		noteStyle:       styles.NoTTYStyle,
+		noteRenderers:   map[int]*glamour.TermRenderer{},
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, watchTick())
 }
 
 func InputActionCallback(m *model, msg tea.Msg, cb interface{}, args ...interface{}) (result []reflect.Value, err error) {
@@ -147,7 +184,19 @@ func HandleInputAction(m *model, msg tea.Msg, handler interface{}) (tea.Model, t
 
 }
 
+// checkTitle refuses a checklist or item title with nothing but spaces.
+func checkTitle(title string) error {
+	if strings.TrimSpace(title) == "" {
+		return errors.New("a title can't be empty")
+	}
+	return nil
+}
+
 func AddChecklistHandler(m *model) {
+	if err := checkTitle(m.textInput.Value()); err != nil {
+		m.err = err
+		return
+	}
 	addChecklist(m.db, m.textInput.Value())
 	updatedList, _ := getChecklists(m.db)
 	m.checklists = updatedList
@@ -159,83 +208,221 @@ func AddChecklistHandler(m *model) {
 }
 
 func AddItemHandler(m *model) {
+	if err := checkTitle(m.textInput.Value()); err != nil {
+		m.err = err
+		return
+	}
 	addItem(m.db, m.textInput.Value(), false, m.activeList)
-	updatedList, _ := getItems(m.db)
-	m.items = updatedList
-	choices := make([]string, len(updatedList))
-	for i, item := range updatedList {
-		choices[i] = item.Title
+	m.reloadItems()
+}
+
+// openChecklist makes the checklist under the cursor the active list and
+// moves the cursor into its items.
+func (m *model) openChecklist() {
+	list, ok := m.cursorChecklist()
+	if !ok {
+		return
+	}
+	m.listCursor = m.cursor
+	m.activeList = list.ID
+	m.activeListTitle = list.Title
+	m.reloadItems()
+	m.layout = ChecklistDetail
+	m.visual = false
+	m.offset = 0
+	m.setCursor(0)
+}
+
+// closeChecklist returns to the checklists with the cursor on the list that
+// was open.
+func (m *model) closeChecklist() {
+	m.activeList = -1
+	lists, _ := getChecklists(m.db)
+	m.checklists = lists
+	m.layout = Checklists
+	m.offset = 0
+	m.setCursor(m.listCursor)
+	m.loadPreview()
+}
+
+// reloadItems reads the active checklist's items back from the database.
+func (m *model) reloadItems() {
+	items, _ := getItemsByChecklistId(m.db, m.activeList)
+	m.items = items
+	m.choices = make([]string, len(items))
+	m.selected = make(map[int]Item, len(items))
+	for i := 0; i < len(items); i++ {
+		m.choices[i] = items[i].Title
+		if items[i].Completed {
+			m.selected[i] = items[i]
+		}
 	}
-	m.choices = choices
 }
 
 func ChecklistDetailAction(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.showInput {
+		if m.editingCommand {
+			return HandleInputAction(&m, msg, SetCommandHandler)
+		}
+		if m.editingTags {
+			return HandleInputAction(&m, msg, SetTagsHandler)
+		}
 		return HandleInputAction(&m, msg, AddItemHandler)
 	}
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-
-		case "ctrl+c", "q":
+		switch {
+		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 
-		case "up", "k":
-			if m.cursor > 0 {
-				m.cursor--
+		case key.Matches(msg, m.keys.Up):
+			m.moveCursor(-1)
+
+		case key.Matches(msg, m.keys.Down):
+			m.moveCursor(1)
+
+		case key.Matches(msg, m.keys.PageUp):
+			m.moveCursor(-m.listHeight())
+
+		case key.Matches(msg, m.keys.PageDown):
+			m.moveCursor(m.listHeight())
+
+		case key.Matches(msg, m.keys.Top):
+			m.moveCursor(-m.listLen())
+
+		case key.Matches(msg, m.keys.Bottom):
+			m.moveCursor(m.listLen())
+
+		case key.Matches(msg, m.keys.Visual):
+			if m.visual {
+				m.visual = false
+			} else {
+				m.startVisual()
 			}
 
-		case "down", "j":
-			if m.cursor < len(m.choices)-1 {
-				m.cursor++
+		case key.Matches(msg, m.keys.SelectUp):
+			m.startVisual()
+			m.moveCursor(-1)
+
+		case key.Matches(msg, m.keys.SelectDown):
+			m.startVisual()
+			m.moveCursor(1)
+
+		case key.Matches(msg, m.keys.Toggle):
+			ids, completed := m.markedIDs(), !m.allMarkedCompleted()
+			if completed {
+				if err := checkBlocked(m.db, ids); err != nil {
+					m.err = err
+					break
+				}
 			}
+			updateItemsCompleted(m.db, ids, completed)
+			m.reloadItems()
 
-		case "enter", " ":
-			_, ok := m.selected[m.cursor]
-			if ok {
-				delete(m.selected, m.cursor)
-				updateItemCompleted(m.db, m.items[m.cursor].ID, false)
-				updatedList, _ := getItems(m.db)
-				m.items = updatedList
+		case key.Matches(msg, m.keys.Next):
+			if i := nextActionable(m.items, m.cursor); i >= 0 {
+				m.visual = false
+				m.setCursor(i)
 			} else {
-				m.selected[m.cursor] = m.items[m.cursor]
-				updateItemCompleted(m.db, m.items[m.cursor].ID, true)
-				updatedList, _ := getItems(m.db)
-				m.items = updatedList
+				m.err = errors.New("nothing left to do")
 			}
 
-		case "x":
-			delete(m.selected, m.cursor)
-			deleteItem(m.db, m.items[m.cursor].ID)
-			updatedList, _ := getItems(m.db)
-			m.items = updatedList
+		case key.Matches(msg, m.keys.Depends):
+			m.openDependencyPicker()
 
-			choices := make([]string, len(updatedList))
-			for i, item := range updatedList {
-				choices[i] = item.Title
+		case key.Matches(msg, m.keys.Delete):
+			if len(m.markedItems()) == 0 {
+				break
 			}
-			m.choices = choices
-			if m.cursor > 1 {
-				m.cursor--
-			} else {
-				m.cursor = 1
+			start, _ := m.markedRange()
+			ids, preview := m.markedIDs(), m.markedTitles()
+			m.openConfirm(deleteQuestion(m.db, itemCount(len(ids))), preview, func(m *model) tea.Cmd {
+				deleteItems(m.db, ids)
+				m.visual = false
+				m.reloadItems()
+				m.setCursor(start)
+				return nil
+			})
+
+		case key.Matches(msg, m.keys.Move):
+			ids, preview := m.markedIDs(), m.markedTitles()
+			if len(ids) == 0 {
+				break
 			}
+			m.openChecklistPicker(fmt.Sprintf("Move %s to:", itemCount(len(ids))), func(m *model, list Checklist) {
+				m.openConfirm(fmt.Sprintf("Move %s to %s?", itemCount(len(ids)), list.Title), preview, func(m *model) tea.Cmd {
+					moveItems(m.db, ids, list.ID)
+					m.visual = false
+					m.reloadItems()
+					m.moveCursor(0)
+					return nil
+				})
+			})
+
+		case key.Matches(msg, m.keys.Copy):
+			ids, preview := m.markedIDs(), m.markedTitles()
+			if len(ids) == 0 {
+				break
+			}
+			m.openChecklistPicker(fmt.Sprintf("Copy %s to:", itemCount(len(ids))), func(m *model, list Checklist) {
+				m.openConfirm(fmt.Sprintf("Copy %s to %s?", itemCount(len(ids)), list.Title), preview, func(m *model) tea.Cmd {
+					copyItems(m.db, ids, list.ID)
+					m.visual = false
+					m.reloadItems()
+					return nil
+				})
+			})
+
+		case key.Matches(msg, m.keys.Note):
+			m.startNoteEdit()
+			return m, textarea.Blink
+
+		case key.Matches(msg, m.keys.NoteEditor):
+			return m, m.openNoteInEditor()
+
+		case key.Matches(msg, m.keys.EditList):
+			return m, m.openListInEditor()
+
+		case key.Matches(msg, m.keys.New):
+			m.editingCommand, m.editingTags = false, false
+			m.textInput.SetValue("")
+			m.showInput = true
 
-		case "n":
+		case key.Matches(msg, m.keys.Retag):
+			marked := m.markedItems()
+			if len(marked) == 0 {
+				break
+			}
+			tags := ""
+			if len(marked) == 1 {
+				tags = marked[0].Tags
+			}
+			m.editingCommand, m.editingTags = false, true
+			m.textInput.SetValue(tags)
 			m.showInput = true
 
-		case "esc":
-			if m.showInput {
-				m.showInput = false
+		case key.Matches(msg, m.keys.Command):
+			if item, ok := m.cursorItem(); ok {
+				m.editingCommand, m.editingTags = true, false
+				m.textInput.SetValue(item.Command)
+				m.showInput = true
 			}
 
-		case "h":
-			m.activeList = -1
-			lists, _ := getChecklists(m.db)
-			m.checklists = lists
-			m.layout = 1
-			m.cursor = 0
+		case key.Matches(msg, m.keys.Run):
+			return m, m.runCursorItem()
+
+		case msg.Type == tea.KeyEsc:
+			m.visual = false
+			if m.run != nil && m.run.done {
+				m.run = nil
+			}
+
+		case key.Matches(msg, m.keys.Back), key.Matches(msg, m.keys.Focus):
+			m.closeChecklist()
+
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = !m.showHelp
 
 		}
 	}
@@ -249,55 +436,139 @@ func ChecklistAction(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "l":
-			m.activeList = m.checklists[m.cursor].ID
-			m.activeListTitle = m.checklists[m.cursor].Title
-			items, _ := getItemsByChecklistId(m.db, m.activeList)
-			m.choices = make([]string, len(items))
-			for i := 0; i < len(items); i++ {
-				m.choices[i] = items[i].Title
-				if items[i].Completed {
-					m.selected[i] = items[i]
-				}
-			}
-			m.cursor = 0
-			m.layout = 2
+		switch {
+		case key.Matches(msg, m.keys.Open), key.Matches(msg, m.keys.Focus):
+			m.openChecklist()
+			return m, nil
 
-		case "ctrl+c", "q":
+		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 
-		case "n":
+		case key.Matches(msg, m.keys.New):
 			m.showInput = true
 
-		case "up", "k":
-			if m.cursor > 0 {
-				m.cursor--
-			}
+		case key.Matches(msg, m.keys.Up):
+			m.moveCursor(-1)
+
+		case key.Matches(msg, m.keys.Down):
+			m.moveCursor(1)
+
+		case key.Matches(msg, m.keys.PageUp):
+			m.moveCursor(-m.listHeight())
+
+		case key.Matches(msg, m.keys.PageDown):
+			m.moveCursor(m.listHeight())
+
+		case key.Matches(msg, m.keys.Top):
+			m.moveCursor(-m.listLen())
 
-		case "down", "j":
-			if m.cursor < len(m.choices)-1 {
-				m.cursor++
+		case key.Matches(msg, m.keys.Bottom):
+			m.moveCursor(m.listLen())
+
+		case key.Matches(msg, m.keys.Delete):
+			if list, ok := m.cursorChecklist(); ok {
+				items, _ := getItemsByChecklistId(m.db, list.ID)
+				preview := []string{fmt.Sprintf("%s (%s)", list.Title, itemCount(len(items)))}
+				m.openConfirm(deleteQuestion(m.db, "this checklist"), preview, func(m *model) tea.Cmd {
+					deleteChecklist(m.db, list.ID)
+					m.checklists, _ = getChecklists(m.db)
+					m.moveCursor(0)
+					m.loadPreview()
+					return nil
+				})
 			}
 
+		case key.Matches(msg, m.keys.Trash):
+			m.openTrash()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Workspace):
+			names := workspaceNames(m.cfg)
+			m.openPicker("Switch workspace:", names, func(m *model, i int) {
+				m.switchWorkspace(names[i])
+			})
+
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = !m.showHelp
+
 		}
 	}
 
+	m.loadPreview()
 	return m, nil
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Errors are shown until the next key.
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.err = nil
+	}
+
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = msg.Width
+		m.height = msg.Height
+		m.help.Width = msg.Width
+		m.offset = clampOffset(m.cursor, m.offset, m.listHeight())
+		m.loadPreview()
+		return m, nil
+	}
+
+	switch msg := msg.(type) {
+	case noteEditedMsg:
+		return NoteEditedAction(m, msg)
+	case listEditedMsg:
+		return ListEditedAction(m, msg)
+	case watchTickMsg:
+		return WatchTickAction(m)
+	case runOutputMsg:
+		return RunOutputAction(m, msg)
+	case runDoneMsg:
+		return RunDoneAction(m, msg)
+	}
+
+	if m.confirming {
+		return ConfirmAction(m, msg)
+	}
+
+	if m.picking {
+		return PickerAction(m, msg)
+	}
+
+	if m.editingNote {
+		return NoteEditAction(m, msg)
+	}
+
 	switch m.layout {
 	case 1:
 		return ChecklistAction(m, msg)
 	case 2:
 		return ChecklistDetailAction(m, msg)
+	case Trash:
+		return TrashAction(m, msg)
 	}
 	return m, nil
 
 }
 
 func (m model) View() string {
+	if m.showHelp {
+		return HelpView(m)
+	}
+	if m.confirming {
+		return ConfirmView(m)
+	}
+	if m.picking {
+		return PickerView(m)
+	}
+	if m.editingNote {
+		return NoteEditView(m)
+	}
+	if m.layout == Trash {
+		return TrashView(m)
+	}
+	if m.isSplit() {
+		return SplitView(m)
+	}
 	switch m.layout {
 	case 1:
 		return ChecklistView(m)
@@ -308,9 +579,11 @@ func (m model) View() string {
 }
 
 func ChecklistView(m model) string {
-	s := "\n  My Checklists\n\n"
+	s := "\n  My Checklists" + workspaceLabel(m) + "\n\n"
 
-	for i, list := range m.checklists {
+	start, end := visibleRange(m.cursor, m.offset, m.listHeight(), len(m.checklists))
+	for i := start; i < end; i++ {
+		list := m.checklists[i]
 		cursor := " "
 		if m.cursor == i {
 			cursor = ">"
@@ -318,25 +591,91 @@ func ChecklistView(m model) string {
 
 		s += fmt.Sprintf("%s %d. %s\n", cursor, list.ID, list.Title)
 	}
+	if len(m.checklists) == 0 {
+		s += "  " + noChecklistsMessage(m) + "\n"
+	}
+	s += "\n  " + pageIndicator(start, end, len(m.checklists)) + "\n"
 
 	if m.showInput {
 		s += fmt.Sprintf(
-			"\nEnter title of new checklist:\n\n%s\n\n%s",
+			"\n%s\n\n%s\n\n%s",
+			m.inputPrompt(),
 			m.textInput.View(),
 			"(esc to quit)",
 		) + "\n"
 	}
 
-	s += "\nPress q to quit.\n"
+	s += errorLine(m)
+	s += "\n" + m.help.View(m.keys) + "\n"
 
 	return s
 }
 
+// noChecklistsMessage and noItemsMessage stand in for an empty list, naming
+// the key that fills it.
+func noChecklistsMessage(m model) string {
+	return fmt.Sprintf("No checklists yet — press %s to create one, or run `chkmrk demo` for samples.", m.keys.New.Help().Key)
+}
+
+func noItemsMessage(m model) string {
+	return fmt.Sprintf("No items — press %s to add one.", m.keys.New.Help().Key)
+}
+
+// itemLabel is how row i of the open checklist reads, locked when it waits
+// on prerequisites, marked when it has a command to run and followed by its
+// tags.
+func (m model) itemLabel(i int, title string) string {
+	if i >= len(m.items) {
+		return title
+	}
+	if m.items[i].Command != "" {
+		title += " " + commandIcon
+	}
+	if m.items[i].Tags != "" {
+		title += " " + tagLabel(m.items[i].Tags)
+	}
+	if m.items[i].Blocked && !m.items[i].Completed {
+		return lockIcon + " " + title
+	}
+	return title
+}
+
+// inputPrompt asks for what the text input is open for.
+func (m model) inputPrompt() string {
+	switch {
+	case m.editingCommand:
+		return "Enter the item's shell command (empty to remove it):"
+	case m.editingTags:
+		return fmt.Sprintf("Enter tags for %s, separated by spaces (empty to remove them):", itemCount(len(m.markedIDs())))
+	case m.layout == ChecklistDetail:
+		return "Enter title of new item:"
+	}
+	return "Enter title of new checklist:"
+}
+
+// errorLine shows the last error, if any, under a view.
+func errorLine(m model) string {
+	if m.err == nil {
+		return ""
+	}
+	return "\n  " + m.err.Error() + "\n"
+}
+
+func HelpView(m model) string {
+	m.help.ShowAll = true
+	return "\n  Keybindings\n\n" + m.help.View(m.keys) + "\n"
+}
+
 func ChecklistDetailView(m model) string {
 	s := fmt.Sprintf("\n  %s\n\n", m.activeListTitle)
 
-	for i, choice := range m.choices {
+	start, end := visibleRange(m.cursor, m.offset, m.listHeight(), len(m.choices))
+	for i := start; i < end; i++ {
+		choice := m.choices[i]
 		cursor := " "
+		if m.isMarked(i) {
+			cursor = "+"
+		}
 		if m.cursor == i {
 			cursor = ">"
 		}
@@ -346,18 +685,26 @@ func ChecklistDetailView(m model) string {
 			checked = "x"
 		}
 
-		s += fmt.Sprintf("%s [%s] %s\n", cursor, checked, choice)
+		s += fmt.Sprintf("%s [%s] %s\n", cursor, checked, m.itemLabel(i, choice))
+	}
+	if len(m.choices) == 0 {
+		s += "  " + noItemsMessage(m) + "\n"
 	}
+	s += "\n  " + pageIndicator(start, end, len(m.choices)) + "\n"
+	s += notePreview(m, m.noteWidth())
+	s += runPane(m, m.noteWidth())
 
 	if m.showInput {
 		s += fmt.Sprintf(
-			"\nEnter title of new item:\n\n%s\n\n%s",
+			"\n%s\n\n%s\n\n%s",
+			m.inputPrompt(),
 			m.textInput.View(),
 			"(esc to quit)",
 		) + "\n"
 	}
 
-	s += "\nPress q to quit.\n"
+	s += errorLine(m)
+	s += "\n" + m.help.View(m.keys) + "\n"
 
 	return s
 }
@@ -368,6 +715,29 @@ type Item struct {
 	Completed   bool
 	Title       string
 	ChecklistID int
+	Note        string
+	// Command is a shell command that does the step; see run.go.
+	Command string
+	// Tags are words separated by single spaces; see tags.go.
+	Tags string
+	// Blocked is set when a prerequisite of the item isn't done yet.
+	Blocked bool
+}
+
+// itemCount says how many items there are, as "1 item" or "3 items".
+func itemCount(n int) string {
+	if n == 1 {
+		return "1 item"
+	}
+	return fmt.Sprintf("%d items", n)
+}
+
+// completedCount is itemCount for checked items.
+func completedCount(n int) string {
+	if n == 1 {
+		return "1 completed item"
+	}
+	return fmt.Sprintf("%d completed items", n)
 }
 
 type Checklist struct {
@@ -388,6 +758,13 @@ func RenderItemInBuffer(w io.Writer, item Item) {
 	} else {
 		fmt.Fprint(w, "[ ]")
 	}
+	if item.Blocked && !item.Completed {
+		fmt.Fprint(w, " "+lockIcon)
+	}
+	if item.Tags != "" {
+		fmt.Fprintf(w, " %s %s\n", item.Title, tagLabel(item.Tags))
+		return
+	}
 	fmt.Fprintf(w, " %s\n", item.Title)
 }
 
@@ -440,40 +817,141 @@ func FindItemInList(list []Item, index int) (Item, error) {
 	return Item{}, errors.New(fmt.Sprintf("Failed to find Item with Index: %d", index))
 }
 
+// initializeDB creates and migrates the schema in one transaction, so two
+// processes opening a new or old database at once can't both migrate it.
 func initializeDB(db *sql.DB) error {
-	checklistsQuery := `
-	CREATE TABLE IF NOT EXISTS checklists (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL
-	);`
+	return withTx(db, func(tx *sql.Tx) error {
+		checklistsQuery := `
+		CREATE TABLE IF NOT EXISTS checklists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL
+		);`
+
+		_, checklistsErr := tx.Exec(checklistsQuery)
+		if checklistsErr != nil {
+			return checklistsErr
+		}
+
+		itemsQuery := `
+		CREATE TABLE IF NOT EXISTS items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			completed BOOLEAN NOT NULL,
+			checklist_id INTEGER,
+			FOREIGN KEY (checklist_id) REFERENCES checklists(id)
+		);
+		`
+		_, itemsErr := tx.Exec(itemsQuery)
+		if itemsErr != nil {
+			return itemsErr
+		}
 
-	_, checklistsErr := db.Exec(checklistsQuery)
-	if checklistsErr != nil {
-		return checklistsErr
+		if err := addColumn(tx, "items", "note", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "items", "position", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "items", "deleted_at", "INTEGER"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "checklists", "deleted_at", "INTEGER"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "items", "depends_on", "TEXT NOT NULL DEFAULT '[]'"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "items", "command", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "items", "tags", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+
+		runsQuery := `
+		CREATE TABLE IF NOT EXISTS runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+			command TEXT NOT NULL,
+			started_at INTEGER NOT NULL,
+			finished_at INTEGER NOT NULL,
+			exit_code INTEGER NOT NULL,
+			output TEXT NOT NULL
+		);`
+		if _, err := tx.Exec(runsQuery); err != nil {
+			return err
+		}
+
+		encryptionQuery := `
+		CREATE TABLE IF NOT EXISTS encryption (
+			salt BLOB NOT NULL,
+			iterations INTEGER NOT NULL,
+			check_value TEXT NOT NULL
+		);`
+		if _, err := tx.Exec(encryptionQuery); err != nil {
+			return err
+		}
+		if err := initializeSync(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion))
+		return err
+	})
+}
+
+// addColumn adds a column to a table created before the column was part of
+// the schema. It does nothing if the column already exists.
+func addColumn(tx *sql.Tx, table string, column string, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
 	}
 
-	itemsQuery := `
-	CREATE TABLE IF NOT EXISTS items (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		completed BOOLEAN NOT NULL,
-    checklist_id INTEGER,
-    FOREIGN KEY (checklist_id) REFERENCES checklists(id)
-	);
-	`
-	_, itemsErr := db.Exec(itemsQuery)
+	var columns []string
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, kind       string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, name)
+	}
+	rows.Close()
+
+	if slices.Contains(columns, column) {
+		return nil
+	}
 
-	return itemsErr
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
 }
 
 func addItem(db *sql.DB, title string, completed bool, checklist_id int) error {
-	query := `INSERT INTO items (title, completed, checklist_id) VALUES (?, ?, ?);`
-	_, err := db.Exec(query, title, completed, checklist_id)
+	title, err := seal(db, title)
+	if err != nil {
+		return err
+	}
+	return withTx(db, func(tx *sql.Tx) error {
+		return insertItem(tx, title, completed, checklist_id)
+	})
+}
+
+// insertItem adds an item, its title already sealed, to the end of a
+// checklist.
+func insertItem(tx *sql.Tx, title string, completed bool, checklist_id int) error {
+	query := `
+	INSERT INTO items (title, completed, checklist_id, position)
+	SELECT ?, ?, ?, COALESCE(MAX(position), 0) + 1 FROM items WHERE checklist_id = ?;`
+	_, err := tx.Exec(query, title, completed, checklist_id, checklist_id)
 	return err
 }
 
 func getChecklists(db *sql.DB) ([]Checklist, error) {
-	query := `SELECT id, title FROM checklists`
+	query := `SELECT id, title FROM checklists WHERE deleted_at IS NULL`
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil, err
@@ -487,13 +965,16 @@ func getChecklists(db *sql.DB) ([]Checklist, error) {
 		if err != nil {
 			return nil, err
 		}
+		if list.Title, err = unseal(db, list.Title); err != nil {
+			return nil, err
+		}
 		lists = append(lists, list)
 	}
 	return lists, nil
 }
 
 func getItems(db *sql.DB) ([]Item, error) {
-	query := `SELECT id, title, completed FROM items`
+	query := `SELECT id, title, completed, note FROM items WHERE deleted_at IS NULL`
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil, err
@@ -503,17 +984,20 @@ func getItems(db *sql.DB) ([]Item, error) {
 	var items []Item
 	for rows.Next() {
 		var item Item
-		err := rows.Scan(&item.ID, &item.Title, &item.Completed)
+		err := rows.Scan(&item.ID, &item.Title, &item.Completed, &item.Note)
 		if err != nil {
 			return nil, err
 		}
+		if err := unsealItem(db, &item); err != nil {
+			return nil, err
+		}
 		items = append(items, item)
 	}
 	return items, nil
 }
 
 func getItemsByChecklistId(db *sql.DB, checklist_id int) ([]Item, error) {
-	query := `SELECT id, title, completed FROM items WHERE checklist_id = ?`
+	query := `SELECT id, title, completed, note, command, tags, ` + blockedColumn + ` FROM items WHERE checklist_id = ? AND deleted_at IS NULL ORDER BY position, id`
 	rows, err := db.Query(query, checklist_id)
 	if err != nil {
 		return nil, err
@@ -523,30 +1007,66 @@ func getItemsByChecklistId(db *sql.DB, checklist_id int) ([]Item, error) {
 	var items []Item
 	for rows.Next() {
 		var item Item
-		err := rows.Scan(&item.ID, &item.Title, &item.Completed)
+		err := rows.Scan(&item.ID, &item.Title, &item.Completed, &item.Note, &item.Command, &item.Tags, &item.Blocked)
 		if err != nil {
 			return nil, err
 		}
+		if err := unsealItem(db, &item); err != nil {
+			return nil, err
+		}
 		items = append(items, item)
 	}
 	return items, nil
 }
 
 func addChecklist(db *sql.DB, title string) error {
+	title, err := seal(db, title)
+	if err != nil {
+		return err
+	}
 	query := `INSERT INTO checklists (title) VALUES (?);`
-	_, err := db.Exec(query, title)
+	_, err = db.Exec(query, title)
 	return err
 
 }
 
 func updateItemCompleted(db *sql.DB, id int, completed bool) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		return setItemCompleted(tx, id, completed)
+	})
+}
+
+func setItemCompleted(tx *sql.Tx, id int, completed bool) error {
 	query := `UPDATE items SET completed = ? WHERE id = ?`
-	_, err := db.Exec(query, completed, id)
+	_, err := tx.Exec(query, completed, id)
+	return err
+}
+
+func updateItemNote(db *sql.DB, id int, note string) error {
+	note, err := seal(db, note)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE items SET note = ? WHERE id = ?`
+	_, err = db.Exec(query, note, id)
+	return err
+}
+
+func updateItemCommand(db *sql.DB, id int, command string) error {
+	command, err := seal(db, command)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE items SET command = ? WHERE id = ?`
+	_, err = db.Exec(query, command, id)
 	return err
 }
 
 func getItemById(db *sql.DB, id int) (Item, error) {
-	query := `SELECT * FROM items WHERE id = ?`
+	query := `
+	SELECT items.id, items.title, items.completed, items.checklist_id, items.note, items.command, items.tags, ` + blockedColumn + `
+	FROM items JOIN checklists ON checklists.id = items.checklist_id
+	WHERE items.id = ? AND items.deleted_at IS NULL AND checklists.deleted_at IS NULL`
 
 	row, err := db.Query(query, id)
 	if err != nil {
@@ -557,185 +1077,211 @@ func getItemById(db *sql.DB, id int) (Item, error) {
 	var item Item
 
 	for row.Next() {
-		err = row.Scan(&item.ID, &item.Title, &item.Completed)
+		err = row.Scan(&item.ID, &item.Title, &item.Completed, &item.ChecklistID, &item.Note, &item.Command, &item.Tags, &item.Blocked)
 		if err != nil {
 			return Item{}, err
 		}
 	}
+	if item.ID == 0 {
+		return Item{}, fmt.Errorf("no item with id %d", id)
+	}
+	if err := unsealItem(db, &item); err != nil {
+		return Item{}, err
+	}
 
 	return item, nil
 }
 
+// deleteItem moves an item to the trash. purgeTrash deletes it for good.
 func deleteItem(db *sql.DB, id int) error {
-	query := `DELETE FROM items WHERE id = ?`
-	_, err := db.Exec(query, id)
+	return withTx(db, func(tx *sql.Tx) error {
+		return trashItem(tx, id)
+	})
+}
+
+func trashItem(tx *sql.Tx, id int) error {
+	query := `UPDATE items SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err := tx.Exec(query, time.Now().Unix(), id)
 	return err
 }
 
-func main() {
+// withTx runs fn inside a transaction, rolling back if it returns an error.
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
 
-	cmd.Execute()
+// execEach runs query, whose last parameter is an item ID, for each of ids
+// in one transaction. An ID that matches no item fails the whole batch.
+func execEach(db *sql.DB, query string, ids []int, args ...interface{}) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			result, err := tx.Exec(query, append(args, id)...)
+			if err != nil {
+				return err
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				return fmt.Errorf("no item with id %d", id)
+			}
+		}
+		return nil
+	})
+}
+
+func updateItemsCompleted(db *sql.DB, ids []int, completed bool) error {
+	query := `UPDATE items SET completed = ? WHERE id = ? AND deleted_at IS NULL`
+	return execEach(db, query, ids, completed)
+}
+
+func deleteItems(db *sql.DB, ids []int) error {
+	query := `UPDATE items SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	return execEach(db, query, ids, time.Now().Unix())
+}
 
-	db, err := sql.Open("sqlite3", "./checklist.db")
+func deleteCompletedItems(db *sql.DB, checklist_id int) (int64, error) {
+	query := `UPDATE items SET deleted_at = ? WHERE checklist_id = ? AND completed = 1 AND deleted_at IS NULL`
+	result, err := db.Exec(query, time.Now().Unix(), checklist_id)
 	if err != nil {
-		log.Fatal(err)
+		return 0, err
 	}
-	defer db.Close()
+	return result.RowsAffected()
+}
 
-	// itemsQuery := `
-	// ALTER TABLE items ADD COLUMN checklist_id INTEGER NOT NULL;
-	// `
-	// _, err = db.Exec(itemsQuery)
-	// if err != nil {
-	// 	log.Fatalf("Fail :%s", err.Error())
-	// }
+// moveItems moves items to the end of another checklist. Dependencies only
+// hold within a checklist, so a moved item loses its prerequisites and the
+// items it leaves behind stop waiting on it.
+func moveItems(db *sql.DB, ids []int, checklist_id int) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			var uuid string
+			var from int
+			err := tx.QueryRow(`SELECT uuid, checklist_id FROM items WHERE id = ? AND deleted_at IS NULL`, id).Scan(&uuid, &from)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("no item with id %d", id)
+			}
+			if err != nil {
+				return err
+			}
 
-	// Initialize the database schema.
-	if err := initializeDB(db); err != nil {
-		log.Fatalf("Initialization error: %s", err.Error())
-	}
+			move := `
+			UPDATE items
+			SET checklist_id = ?, depends_on = '[]', position = (SELECT COALESCE(MAX(position), 0) + 1 FROM items WHERE checklist_id = ?)
+			WHERE id = ?`
+			if _, err := tx.Exec(move, checklist_id, checklist_id, id); err != nil {
+				return err
+			}
 
-	//
-	// // NOTE: This will probably be saved inside a DB or a MD file
-	seedList := []Item{
-		{Index: 1, Completed: true, Title: "make a list of items"},
-		{Index: 2, Completed: true, Title: "test functions that render items"},
-		{Index: 3, Completed: true, Title: "parse cli entrypoint without args"},
-		{Index: 4, Completed: true, Title: "parse cli entrypoint with args"},
-		{Index: 5, Completed: true, Title: "parse add item flag"},
-		{Index: 12, Completed: true, Title: "parse check item flag"},
-		{Index: 11, Completed: true, Title: "parse remove item flag"},
-		{Index: 10, Completed: true, Title: "list args"},
-		{Index: 6, Completed: true, Title: "test check an item"},
-		{Index: 7, Completed: true, Title: "test uncheck an item"},
-		{Index: 8, Completed: true, Title: "test add a new item"},
-		{Index: 9, Completed: true, Title: "test remove an item"},
-		{Index: 13, Completed: true, Title: "create new item from addItemFlag arg, and add to list"},
-		{Index: 14, Completed: true, Title: "test create new item from arg, completed should be false, index should be list len plus 1"},
-		{Index: 15, Completed: false, Title: "create cli process loop"},
-		{Index: 16, Completed: false, Title: "render list in process"},
-		{Index: 17, Completed: false, Title: "render action prompt underneath rendered list in process"},
-		{Index: 18, Completed: false, Title: "capture stdin in process"},
-		{Index: 19, Completed: false, Title: "parse stdin command in process prompt"},
-		{Index: 20, Completed: false, Title: "map stdin command in process prompt to correct action"},
-		{Index: 22, Completed: false, Title: "add identifier to items"},
-		{Index: 23, Completed: true, Title: "add index to render"},
-		{Index: 21, Completed: false, Title: "save list to sqlite db"},
-		{Index: 24, Completed: false, Title: "save list to binary"},
-		{Index: 25, Completed: false, Title: "create checklist table"},
-		{Index: 26, Completed: false, Title: "create item table"},
-		{Index: 27, Completed: false, Title: "create item table"},
-		{Index: 28, Completed: false, Title: "refactor flag parsing"},
-	}
-
-	checklists, err := getChecklists(db)
-	if len(checklists) == 0 {
-		err := addChecklist(db, "My First Checklist")
-		if err != nil {
-			fmt.Printf("Error adding checklist: %s", err.Error())
+			unlink := `
+			UPDATE items
+			SET depends_on = (SELECT json_group_array(value) FROM json_each(items.depends_on) WHERE value != ?1)
+			WHERE checklist_id = ?2 AND EXISTS (SELECT 1 FROM json_each(items.depends_on) WHERE value = ?1)`
+			if _, err := tx.Exec(unlink, uuid, from); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
+
+func copyItems(db *sql.DB, ids []int, checklist_id int) error {
+	query := `
+	INSERT INTO items (title, completed, checklist_id, note, position)
+	SELECT title, completed, ?, note, (SELECT COALESCE(MAX(position), 0) + 1 FROM items WHERE checklist_id = ?)
+	FROM items WHERE id = ? AND deleted_at IS NULL;`
+	return execEach(db, query, ids, checklist_id, checklist_id)
+}
+
+// reorderItems puts the checklist's items in the order of ids, which must
+// name every item in it.
+func reorderItems(db *sql.DB, checklist_id int, ids []int) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		return setItemOrder(tx, checklist_id, ids)
+	})
+}
+
+// setItemOrder gives the items of a checklist the order of ids, which must
+// name each of them once.
+func setItemOrder(tx *sql.Tx, checklist_id int, ids []int) error {
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM items WHERE checklist_id = ? AND deleted_at IS NULL`, checklist_id).Scan(&count); err != nil {
+		return err
+	}
+	if count != len(ids) {
+		return fmt.Errorf("order has %d items; checklist %d has %d", len(ids), checklist_id, count)
 	}
 
-	dbItems, err := getItems(db)
-	if len(dbItems) == 0 {
-		firstList, err := getChecklists(db)
+	query := `UPDATE items SET position = ? WHERE id = ? AND checklist_id = ?`
+	seen := make(map[int]bool, len(ids))
+	for i, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("item %d appears twice in the order", id)
+		}
+		seen[id] = true
+		result, err := tx.Exec(query, i+1, id, checklist_id)
 		if err != nil {
-			log.Printf("ERR: %s", err.Error())
+			return err
 		}
-		firstId := firstList[0].ID
-		for i := 0; i < len(seedList); i++ {
-			err := addItem(db, seedList[i].Title, seedList[i].Completed, firstId)
-			if err != nil {
-				log.Printf("Error adding item in seed: %s", err.Error())
-			}
+		if n, _ := result.RowsAffected(); n != 1 {
+			return fmt.Errorf("no item with id %d in checklist %d", id, checklist_id)
 		}
 	}
+	return nil
+}
+
+func main() {
+	cmd.AddCommand(initCmd, demoCmd, checkCmd, uncheckCmd, rmCmd, mvCmd, cpCmd, showCmd, editListCmd, serveCmd, webCmd, daemonCmd, syncCmd, shareCmd, receiveCmd, dbCmd, backupCmd, trashCmd, depsCmd, commandCmd, retagCmd)
+	cmd.Process = runTUI
+	cmd.Execute()
+}
+
+func runTUI() {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Error loading config: %s", err.Error())
+	}
+
+	db, closeDB, workspace, err := openCurrent(cfg)
+	if err != nil {
+		log.Fatalf("Initialization error: %s", err.Error())
+	}
+	allowPrompt = false
 
-	p := tea.NewProgram(initialModel(db))
-	if _, err := p.Run(); err != nil {
+	keys, err := newKeyMap(cfg)
+	if err != nil {
+		log.Printf("Error loading keymap: %s", err.Error())
+	}
+
+	m := initialModel(db, keys)
+	m.noteStyle = detectNoteStyle()
+	m.cfg = cfg
+	m.workspace = workspace
+	m.closeDB = closeDB
+	m.watcher, err = newChangeWatcher(db)
+	if err != nil {
+		log.Printf("Error watching for changes: %s", err.Error())
+	}
+
+	p := tea.NewProgram(m)
+	final, err := p.Run()
+	switch final := final.(type) {
+	case model:
+		final.run.stop()
+		final.watcher.Close()
+		final.closeDB()
+	case *model:
+		final.run.stop()
+		final.watcher.Close()
+		final.closeDB()
+	}
+	if err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
 	}
-	// // NOTE: We can probably extract this to a new function
-	// flag.StringVar(&addItemFlag, "a", "default", "help message")
-	// flag.IntVar(&checkItemFlag, "c", 0, "help message")
-	// flag.IntVar(&removeItemFlag, "r", 0, "help message")
-	// flag.BoolVar(&listItemsFlag, "l", false, "help message")
-	// flag.Parse()
-	//
-	// var buffer bytes.Buffer
-	//
-	// if addItemFlag != "default" {
-	// 	err := addItem(db, addItemFlag, false)
-	// 	if err != nil {
-	// 		log.Fatalf("Error adding item: %s", err.Error())
-	// 	}
-	//
-	// 	list, err := getItems(db)
-	// 	if err != nil {
-	// 		log.Printf("Error getting items after adding new item")
-	// 	}
-	//
-	// 	RenderListInBuffer(&buffer, list)
-	// 	fmt.Print(buffer.String())
-	// }
-	//
-	// if checkItemFlag != 0 {
-	// 	item, err := getItemById(db, checkItemFlag)
-	// 	if err != nil {
-	// 		log.Fatalf("Error finding item with id %d: %s", checkItemFlag, err.Error())
-	// 	}
-	//
-	// 	if !item.Completed {
-	// 		updateItemCompleted(db, item.ID, true)
-	// 		list, err := getItems(db)
-	// 		if err != nil {
-	// 			fmt.Errorf("Error updating item: %s", err.Error())
-	// 		}
-	// 		RenderListInBuffer(&buffer, list)
-	// 		fmt.Print(buffer.String())
-	// 	} else {
-	// 		updateItemCompleted(db, item.ID, false)
-	// 		list, err := getItems(db)
-	// 		if err != nil {
-	// 			fmt.Errorf("Error updating item: %s", err.Error())
-	// 		}
-	// 		RenderListInBuffer(&buffer, list)
-	// 		fmt.Print(buffer.String())
-	// 	}
-	// }
-	//
-	// if removeItemFlag != 0 {
-	// 	item, err := getItemById(db, removeItemFlag)
-	// 	if err != nil {
-	// 		log.Fatalf("Error finding item with ID %d: %s", removeItemFlag, err.Error())
-	// 	}
-	//
-	// 	list, err := getItems(db)
-	// 	if err != nil {
-	// 		fmt.Errorf("Error updating item: %s", err.Error())
-	// 	}
-	//
-	// 	err = deleteItem(db, item.ID)
-	// 	if err != nil {
-	// 		log.Fatalf("Error deleteing item: %s", err.Error())
-	// 	}
-	//
-	// 	list, err = getItems(db)
-	// 	if err != nil {
-	// 		log.Println(err)
-	// 	}
-	//
-	// 	RenderListInBuffer(&buffer, list)
-	// 	fmt.Print(buffer.String())
-	// }
-	//
-	// if listItemsFlag {
-	// 	dbList, err := getItems(db)
-	// 	if err != nil {
-	// 		log.Fatalf("Unable to list items: %s", err)
-	// 	}
-	// 	RenderListInBuffer(&buffer, dbList)
-	// 	fmt.Print(buffer.String())
-	// }
 }
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// markdownFiles lists the checklist files of a markdown directory in file
+// name order, which is the order their checklists are loaded in.
+func markdownFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	sort.Strings(files)
+	return files, err
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// openMarkdownDir loads a directory of markdown checklists, one per file,
+// into an in-memory database. Each change is written back to the files as
+// it is made.
+func openMarkdownDir(dir string) (*sql.DB, func() error, error) {
+	paths, err := markdownFiles(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ws := &markdownWorkspace{dir: dir}
+	for _, path := range paths {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		ws.files = append(ws.files, parseMarkdownChecklist(path, string(text)))
+	}
+	return openMarkdown(ws)
+}
+
+// markdownApplicationID is the application_id of the in-memory database a
+// markdown workspace is loaded into, so code that only has the *sql.DB,
+// even through a daemon, can tell.
+const markdownApplicationID = 0x63686b6d
+
+// isMarkdown reports whether db holds a markdown workspace.
+func isMarkdown(db *sql.DB) bool {
+	var id int
+	db.QueryRow(`PRAGMA application_id`).Scan(&id)
+	return id == markdownApplicationID
+}
+
+// markdownWorkspace is markdown checklists loaded into an in-memory
+// database: a directory with a checklist per file, or one CHECKLIST.md with
+// a checklist per "# " heading. Every change to the database is written
+// back before it commits, rewriting only the task lines that changed, and
+// refused if a file changed on disk since it was read. There is no trash:
+// what is deleted is gone.
+type markdownWorkspace struct {
+	// dir is where new checklists get a file of their own. Without one
+	// they are added to the end of the only file.
+	dir   string
+	files []*markdownFile
+	ready bool
+}
+
+// markdownFile is a file of a markdown workspace as last read or written.
+type markdownFile struct {
+	path     string
+	text     string
+	sections []markdownSection
+}
+
+// markdownSection is where in its file a checklist is: lines [start, end),
+// with its "# " title at line heading, or -1 if it has none. The line of
+// each task carries the ID of the item it is.
+type markdownSection struct {
+	checklistID int
+	title       string
+	start, end  int
+	heading     int
+	tasks       []taskBlock
+}
+
+// markdownList is a checklist as the database has it mid-change.
+type markdownList struct {
+	title string
+	items []Item
+}
+
+// writtenTask is a task as it was last read or written.
+type writtenTask struct {
+	lines []string
+	line  editLine
+}
+
+// fileLines splits text into lines, leaving out the empty one after a
+// final newline.
+func fileLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// parseMarkdownChecklist reads a file of a markdown directory: one checklist
+// titled by its first "# " heading, else by the file name.
+func parseMarkdownChecklist(path, text string) *markdownFile {
+	lines := fileLines(text)
+	s := markdownSection{
+		title:   strings.TrimSuffix(filepath.Base(path), ".md"),
+		end:     len(lines),
+		heading: -1,
+		tasks:   parseTaskBlocks(lines),
+	}
+	for i, line := range lines {
+		if title, ok := strings.CutPrefix(line, "# "); ok {
+			s.title, s.heading = strings.TrimSpace(title), i
+			break
+		}
+	}
+	return &markdownFile{path: path, text: text, sections: []markdownSection{s}}
+}
+
+// openMarkdown loads ws into an in-memory database.
+func openMarkdown(ws *markdownWorkspace) (*sql.DB, func() error, error) {
+	db := sql.OpenDB(markdownConnector{ws: ws})
+	// Every connection to :memory: is a separate database.
+	db.SetMaxOpenConns(1)
+	if err := ws.load(db); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	ws.ready = true
+	return db, db.Close, nil
+}
+
+func (ws *markdownWorkspace) load(db *sql.DB) error {
+	if err := initializeDB(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec(fmt.Sprintf(`PRAGMA application_id = %d`, markdownApplicationID)); err != nil {
+		return err
+	}
+
+	for _, f := range ws.files {
+		for i := range f.sections {
+			s := &f.sections[i]
+			t := checklistTemplate{Title: s.title}
+			for _, task := range s.tasks {
+				t.Lines = append(t.Lines, task.line)
+			}
+			id, err := addChecklistFromTemplate(db, t)
+			if err != nil {
+				return err
+			}
+			items, err := getItemsByChecklistId(db, id)
+			if err != nil {
+				return err
+			}
+			s.checklistID = id
+			for j := range s.tasks {
+				s.tasks[j].line.ID = items[j].ID
+			}
+		}
+	}
+	return nil
+}
+
+// save writes back the files that db, in the middle of a change, no longer
+// matches. Every file is checked before any is written, so a refused change
+// writes nothing.
+func (ws *markdownWorkspace) save(db *sql.DB) error {
+	// Markdown has nowhere to keep a trash, so deletes are for good.
+	purge := []string{
+		`DELETE FROM items WHERE deleted_at IS NOT NULL OR checklist_id IN (SELECT id FROM checklists WHERE deleted_at IS NOT NULL)`,
+		`DELETE FROM checklists WHERE deleted_at IS NOT NULL`,
+	}
+	for _, query := range purge {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	// Nor anywhere to keep what isn't a task line.
+	unstored := []struct{ column, unset, what string }{
+		{"depends_on", "[]", "dependencies"},
+		{"command", "", "commands"},
+		{"tags", "", "tags"},
+	}
+	for _, u := range unstored {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM items WHERE `+u.column+` != ?`, u.unset).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("markdown checklists can't store %s; use a chkmrk database for them", u.what)
+		}
+	}
+
+	lists, err := getChecklists(db)
+	if err != nil {
+		return err
+	}
+	current := make(map[int]markdownList, len(lists))
+	for _, list := range lists {
+		items, err := getItemsByChecklistId(db, list.ID)
+		if err != nil {
+			return err
+		}
+		current[list.ID] = markdownList{title: list.Title, items: items}
+	}
+
+	written := map[int]writtenTask{}
+	placed := map[int]bool{}
+	taken := map[string]bool{}
+	for _, f := range ws.files {
+		lines := fileLines(f.text)
+		for _, s := range f.sections {
+			placed[s.checklistID] = true
+			for _, t := range s.tasks {
+				written[t.line.ID] = writtenTask{lines: lines[t.start:t.end], line: t.line}
+			}
+		}
+		taken[f.path] = true
+	}
+
+	// Checklists the files don't have yet go at the end of the only file,
+	// or into new files of a directory.
+	files := ws.files
+	adds := make([][]int, len(files))
+	for _, list := range lists {
+		if placed[list.ID] {
+			continue
+		}
+		if ws.dir == "" {
+			adds[0] = append(adds[0], list.ID)
+			continue
+		}
+		path := newChecklistPath(ws.dir, list.Title, taken)
+		taken[path] = true
+		files = append(files, &markdownFile{path: path})
+		adds = append(adds, []int{list.ID})
+	}
+
+	texts := make([]string, len(files))
+	sections := make([][]markdownSection, len(files))
+	for i, f := range files {
+		texts[i], sections[i] = f.render(current, written, adds[i])
+		if texts[i] != f.text {
+			if err := f.checkUnchanged(); err != nil {
+				return err
+			}
+		}
+	}
+
+	var kept []*markdownFile
+	for i, f := range files {
+		switch {
+		case ws.dir != "" && len(sections[i]) == 0:
+			// The checklist of this file was deleted.
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		case texts[i] != f.text:
+			if err := writeFileAtomic(f.path, []byte(texts[i])); err != nil {
+				return err
+			}
+		}
+		f.text, f.sections = texts[i], sections[i]
+		kept = append(kept, f)
+	}
+	ws.files = kept
+	return nil
+}
+
+// checkUnchanged returns an error if f changed on disk since it was last
+// read or written.
+func (f *markdownFile) checkUnchanged() error {
+	text, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) && f.text == "" {
+		return nil
+	}
+	if err != nil || string(text) != f.text {
+		return fmt.Errorf("%s changed on disk since chkmrk read it; reopen it to pick up the changes", f.path)
+	}
+	return nil
+}
+
+// render lays the checklists out over the file as last written. Prose stays
+// where it is, tasks that didn't change keep their lines, and the others are
+// written afresh in the places of the tasks that were there. Checklists that
+// are gone lose their lines, and those in add are appended.
+func (f *markdownFile) render(lists map[int]markdownList, written map[int]writtenTask, add []int) (string, []markdownSection) {
+	lines := fileLines(f.text)
+	var out []string
+	var sections []markdownSection
+
+	pos := 0
+	for _, s := range f.sections {
+		out = append(out, lines[pos:s.start]...)
+		pos = s.end
+		if list, ok := lists[s.checklistID]; ok {
+			var section markdownSection
+			out, section = s.render(out, lines, list, written)
+			sections = append(sections, section)
+		}
+	}
+	out = append(out, lines[pos:]...)
+
+	for _, id := range add {
+		if len(out) > 0 && out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+		s := markdownSection{checklistID: id, title: lists[id].title, start: len(out), heading: len(out)}
+		out = append(out, "# "+s.title)
+		if len(lists[id].items) > 0 {
+			out = append(out, "")
+		}
+		for _, item := range lists[id].items {
+			out, s.tasks = placeTask(out, s.tasks, item, written)
+		}
+		s.end = len(out)
+		sections = append(sections, s)
+	}
+
+	text := strings.Join(out, "\n")
+	if len(out) > 0 && (f.text == "" || strings.HasSuffix(f.text, "\n")) {
+		text += "\n"
+	}
+	return text, sections
+}
+
+// render appends the section, holding list now, to out.
+func (s markdownSection) render(out, lines []string, list markdownList, written map[int]writtenTask) ([]string, markdownSection) {
+	section := markdownSection{checklistID: s.checklistID, title: list.title, start: len(out), heading: -1}
+	items := list.items
+	next := 0
+
+	// Without tasks to take the place of, new ones go after the last line
+	// of text.
+	insert := -1
+	if len(s.tasks) == 0 {
+		insert = s.end
+		for insert > s.start && lines[insert-1] == "" {
+			insert--
+		}
+		insert = max(insert, s.heading+1)
+	}
+	if s.heading < 0 && list.title != s.title {
+		section.heading = len(out)
+		out = append(out, "# "+list.title, "")
+	}
+
+	slot := 0
+	for i := s.start; i <= s.end; {
+		if i == insert && next < len(items) {
+			out = append(out, "")
+			for ; next < len(items); next++ {
+				out, section.tasks = placeTask(out, section.tasks, items[next], written)
+			}
+		}
+		if i == s.end {
+			break
+		}
+
+		if slot < len(s.tasks) && i == s.tasks[slot].start {
+			if next < len(items) {
+				out, section.tasks = placeTask(out, section.tasks, items[next], written)
+				next++
+			}
+			if slot == len(s.tasks)-1 {
+				for ; next < len(items); next++ {
+					out, section.tasks = placeTask(out, section.tasks, items[next], written)
+				}
+			}
+			i = s.tasks[slot].end
+			slot++
+			continue
+		}
+
+		line := lines[i]
+		if i == s.heading {
+			section.heading = len(out)
+			if list.title != s.title {
+				line = "# " + list.title
+			}
+		}
+		out = append(out, line)
+		i++
+	}
+	section.end = len(out)
+	return out, section
+}
+
+// placeTask appends the lines of item to out and records where they are.
+func placeTask(out []string, tasks []taskBlock, item Item, written map[int]writtenTask) ([]string, []taskBlock) {
+	line := editLine{ID: item.ID, Title: item.Title, Completed: item.Completed, Note: item.Note}
+	start := len(out)
+	out = append(out, taskLines(line, written[item.ID])...)
+	return out, append(tasks, taskBlock{start: start, end: len(out), line: line})
+}
+
+// taskLines returns the lines of a task: the ones it was written as if it
+// didn't change, else with the checkbox, title or note rewritten.
+func taskLines(line editLine, was writtenTask) []string {
+	if was.line == line {
+		return was.lines
+	}
+
+	checked := " "
+	if line.Completed {
+		checked = "x"
+	}
+	var lines []string
+	if match := editLinePattern.FindStringSubmatchIndex(firstLine(was.lines)); match != nil && was.line.Title == line.Title {
+		task := was.lines[0]
+		lines = append(lines, task[:match[2]]+checked+task[match[3]:])
+	} else {
+		lines = append(lines, fmt.Sprintf("- [%s] %s", checked, line.Title))
+	}
+
+	if len(was.lines) > 0 && was.line.Note == line.Note {
+		return append(lines, was.lines[1:]...)
+	}
+	if line.Note != "" {
+		for _, noteLine := range strings.Split(line.Note, "\n") {
+			if noteLine != "" {
+				noteLine = "  " + noteLine
+			}
+			lines = append(lines, noteLine)
+		}
+	}
+	return lines
+}
+
+func firstLine(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[0]
+}
+
+// newChecklistPath returns a file in dir, not yet on disk or in taken, for
+// a checklist titled title.
+func newChecklistPath(dir, title string, taken map[string]bool) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if slug == "" {
+		slug = "checklist"
+	}
+
+	path := filepath.Join(dir, slug+".md")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) && !taken[path] {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.md", slug, n))
+	}
+}
+
+// markdownConnector opens the in-memory database of a markdown workspace.
+// Its connection writes the files back inside each change, just before it
+// commits, and fails the change if they can't be written.
+type markdownConnector struct {
+	ws *markdownWorkspace
+}
+
+func (c markdownConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(sqliteDSN(":memory:"))
+	if err != nil {
+		return nil, err
+	}
+	sqliteConn := conn.(*sqlite3.SQLiteConn)
+	return &markdownConn{
+		SQLiteConn: sqliteConn,
+		ws:         c.ws,
+		view:       sql.OpenDB(heldConnector{sqliteConn}),
+	}, nil
+}
+
+func (c markdownConnector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+type markdownConn struct {
+	*sqlite3.SQLiteConn
+	ws *markdownWorkspace
+	// view reads this connection while database/sql has it busy with the
+	// change being saved.
+	view *sql.DB
+}
+
+// ExecContext runs a statement outside a transaction in a savepoint, so the
+// files can be written before it takes effect.
+func (c *markdownConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !c.ws.ready || !c.AutoCommit() {
+		return c.SQLiteConn.ExecContext(ctx, query, args)
+	}
+
+	if _, err := c.SQLiteConn.ExecContext(ctx, `SAVEPOINT markdown`, nil); err != nil {
+		return nil, err
+	}
+	result, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	if err == nil {
+		err = c.ws.save(c.view)
+	}
+	if err != nil {
+		c.SQLiteConn.ExecContext(ctx, `ROLLBACK TO markdown; RELEASE markdown`, nil)
+		return nil, err
+	}
+	if _, err := c.SQLiteConn.ExecContext(ctx, `RELEASE markdown`, nil); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *markdownConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.SQLiteConn.BeginTx(ctx, opts)
+	if err != nil || !c.ws.ready {
+		return tx, err
+	}
+	return markdownTx{Tx: tx, c: c}, nil
+}
+
+func (c *markdownConn) Close() error {
+	c.view.Close()
+	return c.SQLiteConn.Close()
+}
+
+type markdownTx struct {
+	driver.Tx
+	c *markdownConn
+}
+
+func (tx markdownTx) Commit() error {
+	if err := tx.c.ws.save(tx.c.view); err != nil {
+		tx.Tx.Rollback()
+		return err
+	}
+	return tx.Tx.Commit()
+}
+
+// heldConnector lends a connection to a second *sql.DB, which must only use
+// it while the first one can't.
+type heldConnector struct {
+	conn *sqlite3.SQLiteConn
+}
+
+func (c heldConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return heldConn{c.conn}, nil
+}
+
+func (c heldConnector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+// heldConn is a lent connection; closing it is up to its owner.
+type heldConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (heldConn) Close() error {
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdir runs the rest of the test in dir.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestMarkdownDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, projectDir)
+	os.Mkdir(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "deploy.md"), []byte("# Deploy\n\nRun from the bastion host.\n\n- [ ] Drain\n- [ ] Roll out\n  one region at a time\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "review.md"), []byte("- [x] Read diff\n"), 0o644)
+	t.Setenv("CHKMRK_CONFIG", filepath.Join(root, "config.json"))
+	chdir(t, root)
+
+	// The IDs the CLI takes are the ones it lists.
+	if err := setCompleted([]string{"1"}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+	if err := moveItems(db, []int{2}, 2); err != nil {
+		t.Fatal(err)
+	}
+	addChecklist(db, "Release v2!")
+	addChecklist(db, "Release v2")
+	addItem(db, "Tag", true, 3)
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"deploy.md", "# Deploy\n\nRun from the bastion host.\n\n- [x] Drain\n"},
+		{"review.md", "- [x] Read diff\n- [ ] Roll out\n  one region at a time\n"},
+		{"release-v2.md", "# Release v2!\n\n- [x] Tag\n"},
+		{"release-v2-2.md", "# Release v2\n"},
+	}
+	for index, test := range tests {
+		actual, err := os.ReadFile(filepath.Join(dir, test.file))
+		if err != nil || string(actual) != test.expected {
+			t.Errorf("Test number %d -> %s = %q, %v; expected %q", index, test.file, actual, err, test.expected)
+		}
+	}
+
+	lists, _ := getChecklists(db)
+	if len(lists) != 4 || lists[1].Title != "review" {
+		t.Errorf("getChecklists() = %v; expected a checklist named after review.md", lists)
+	}
+}
+
+func TestMarkdownDeletesForGood(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), projectDir)
+	os.Mkdir(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "deploy.md"), []byte("# Deploy\n\n- [ ] Drain\n- [ ] Roll out\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "review.md"), []byte("- [x] Read diff\n"), 0o644)
+
+	db, closeDB, err := openMarkdownDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+	if err := deleteItems(db, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteChecklist(db, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, _ := os.ReadFile(filepath.Join(dir, "deploy.md")); string(actual) != "# Deploy\n\n- [ ] Roll out\n" {
+		t.Errorf("deploy.md = %q; expected Drain gone", actual)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "review.md")); !os.IsNotExist(err) {
+		t.Errorf("review.md is still there: %v", err)
+	}
+	if trash, err := getTrash(db); err != nil || len(trash) != 0 {
+		t.Errorf("getTrash() = %v, %v; expected nothing kept", trash, err)
+	}
+	if question := deleteQuestion(db, "1 items"); !strings.Contains(question, "for good") {
+		t.Errorf("deleteQuestion() = %q; expected it to say the delete is for good", question)
+	}
+}
+
+func TestMarkdownRefusesUnstored(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), projectDir)
+	os.Mkdir(dir, 0o755)
+	text := "- [ ] Test\n- [ ] Tag\n"
+	os.WriteFile(filepath.Join(dir, "release.md"), []byte(text), 0o644)
+
+	db, closeDB, err := openMarkdownDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+
+	tests := []struct {
+		change   func() error
+		expected string
+	}{
+		{func() error { return setDependencies(db, 2, []int{1}) }, "can't store dependencies"},
+		{func() error { return updateItemCommand(db, 2, "git tag v2") }, "can't store commands"},
+		{func() error { return retagItems(db, []int{2}, "release") }, "can't store tags"},
+	}
+	for index, test := range tests {
+		if err := test.change(); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Test number %d -> error = %v; expected %q", index, err, test.expected)
+		}
+	}
+
+	item, _ := getItemById(db, 2)
+	if actual, _ := os.ReadFile(filepath.Join(dir, "release.md")); string(actual) != text || item.Command != "" || item.Blocked {
+		t.Errorf("a refused change was kept: %q, %+v", actual, item)
+	}
+}
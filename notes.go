@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+)
+
+type noteEditedMsg struct {
+	id   int
+	path string
+	err  error
+}
+
+// detectNoteStyle picks the glamour style of notes for the terminal's
+// background. It asks the terminal, so it must run before the TUI takes
+// over the terminal's input.
+func detectNoteStyle() string {
+	switch {
+	case !term.IsTerminal(int(os.Stdout.Fd())):
+		return styles.NoTTYStyle
+	case lipgloss.HasDarkBackground():
+		return styles.DarkStyle
+	default:
+		return styles.LightStyle
+	}
+}
+
+// renderNote renders note as markdown wrapped at width. The model keeps one
+// glamour renderer per width, since building a renderer is much more
+// expensive than rendering with it.
+func (m *model) renderNote(note string, width int) string {
+	if m.noteRenderers == nil {
+		m.noteRenderers = map[int]*glamour.TermRenderer{}
+	}
+	r, ok := m.noteRenderers[width]
+	if !ok {
+		var err error
+		r, err = glamour.NewTermRenderer(glamour.WithStandardStyle(m.noteStyle), glamour.WithWordWrap(width))
+		if err != nil {
+			return note
+		}
+		m.noteRenderers[width] = r
+	}
+
+	out, err := r.Render(note)
+	if err != nil {
+		return note
+	}
+	return strings.Trim(out, "\n")
+}
+
+// editorCmd returns the command that opens path in the user's editor.
+func editorCmd(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+func (m *model) startNoteEdit() {
+	item, ok := m.cursorItem()
+	if !ok {
+		return
+	}
+
+	ta := textarea.New()
+	ta.CharLimit = 0
+	ta.SetWidth(60)
+	ta.SetHeight(10)
+	ta.SetValue(item.Note)
+	ta.Focus()
+
+	m.noteInput = ta
+	m.noteItemID = item.ID
+	m.editingNote = true
+}
+
+// openNoteInEditor writes the note under the cursor to a temp file and
+// hands the terminal to $EDITOR until it exits.
+func (m model) openNoteInEditor() tea.Cmd {
+	item, ok := m.cursorItem()
+	if !ok {
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "chkmrk-note-*.md")
+	if err != nil {
+		return func() tea.Msg { return errMsg(err) }
+	}
+	f.WriteString(item.Note)
+	f.Close()
+
+	return tea.ExecProcess(editorCmd(f.Name()), func(err error) tea.Msg {
+		return noteEditedMsg{id: item.ID, path: f.Name(), err: err}
+	})
+}
+
+func NoteEditedAction(m model, msg noteEditedMsg) (tea.Model, tea.Cmd) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+
+	note, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	updateItemNote(m.db, msg.id, strings.TrimRight(string(note), "\n"))
+	m.reloadItems()
+	return m, nil
+}
+
+func NoteEditAction(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+
+		case tea.KeyEsc:
+			m.editingNote = false
+			return m, nil
+
+		case tea.KeyCtrlS:
+			updateItemNote(m.db, m.noteItemID, m.noteInput.Value())
+			m.editingNote = false
+			m.reloadItems()
+			return m, nil
+		}
+	}
+
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd
+}
+
+func NoteEditView(m model) string {
+	return "\n  Note\n\n" + m.noteInput.View() + "\n\n(ctrl+s to save, esc to cancel)\n"
+}
+
+func (m model) noteWidth() int {
+	if m.width == 0 {
+		return 80
+	}
+	return m.width
+}
+
+// notePreview renders the note of the item under the cursor, if it has one.
+func notePreview(m model, width int) string {
+	item, ok := m.cursorItem()
+	if !ok || item.Note == "" {
+		return ""
+	}
+	return "\n" + m.renderNote(item.Note, width) + "\n"
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditorCmd(t *testing.T) {
+	tests := []struct {
+		visual   string
+		editor   string
+		expected string
+	}{
+		{"", "", "vi note.md"},
+		{"  ", "", "vi note.md"},
+		{"", "nano", "nano note.md"},
+		{"code --wait", "nano", "code --wait note.md"},
+	}
+	for index, test := range tests {
+		t.Setenv("VISUAL", test.visual)
+		t.Setenv("EDITOR", test.editor)
+		if got := strings.Join(editorCmd("note.md").Args, " "); got != test.expected {
+			t.Errorf("Test number %d -> editorCmd() = %q; expected %q", index, got, test.expected)
+		}
+	}
+}
+
+func TestRenderNoteWithoutRenderers(t *testing.T) {
+	var m model
+	if got := m.renderNote("**done**", 40); !strings.Contains(got, "done") {
+		t.Errorf("renderNote() = %q; expected the note's text", got)
+	}
+}
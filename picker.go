@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openPicker asks the user to choose one of options and calls onPick with
+// its index.
+func (m *model) openPicker(title string, options []string, onPick func(m *model, i int)) {
+	m.picking = true
+	m.pickTitle = title
+	m.pickOptions = options
+	m.pickCursor = 0
+	m.onPick = onPick
+}
+
+// openChecklistPicker asks the user to choose a checklist.
+func (m *model) openChecklistPicker(title string, onPick func(m *model, list Checklist)) {
+	lists, _ := getChecklists(m.db)
+	m.checklists = lists
+
+	options := make([]string, len(lists))
+	for i, list := range lists {
+		options[i] = fmt.Sprintf("%d. %s", list.ID, list.Title)
+	}
+	m.openPicker(title, options, func(m *model, i int) {
+		onPick(m, lists[i])
+	})
+}
+
+func PickerAction(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case msg.Type == tea.KeyCtrlC:
+			return m, tea.Quit
+
+		case msg.Type == tea.KeyEsc:
+			m.picking = false
+
+		case msg.Type == tea.KeyEnter:
+			m.picking = false
+			if m.pickCursor < len(m.pickOptions) {
+				m.onPick(&m, m.pickCursor)
+			}
+
+		case key.Matches(msg, m.keys.Up):
+			if m.pickCursor > 0 {
+				m.pickCursor--
+			}
+
+		case key.Matches(msg, m.keys.Down):
+			if m.pickCursor < len(m.pickOptions)-1 {
+				m.pickCursor++
+			}
+
+		}
+	}
+
+	return m, nil
+}
+
+func PickerView(m model) string {
+	s := fmt.Sprintf("\n  %s\n\n", m.pickTitle)
+
+	for i, option := range m.pickOptions {
+		cursor := " "
+		if m.pickCursor == i {
+			cursor = ">"
+		}
+
+		s += fmt.Sprintf("%s %s\n", cursor, option)
+	}
+	if len(m.pickOptions) == 0 {
+		s += "  Nothing to choose from.\n"
+	}
+
+	s += "\n(enter to choose, esc to cancel)\n"
+
+	return s
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	projectDir  = ".chkmrk"
+	projectFile = "CHECKLIST.md"
+)
+
+// findProject walks up from dir looking for a .chkmrk directory or a
+// CHECKLIST.md file, the way git looks for .git, and returns the path of the
+// first one it finds.
+func findProject(dir string) (string, bool) {
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, projectDir)); err == nil && fi.IsDir() {
+			return filepath.Join(dir, projectDir), true
+		}
+		if fi, err := os.Stat(filepath.Join(dir, projectFile)); err == nil && !fi.IsDir() {
+			return filepath.Join(dir, projectFile), true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// projectName is the name of the directory holding the project marker.
+func projectName(marker string) string {
+	return filepath.Base(filepath.Dir(marker))
+}
+
+// projectDBPath returns the database that backs a project marker. For a
+// CHECKLIST.md this is the markdown file itself.
+func projectDBPath(marker string) string {
+	if filepath.Base(marker) == projectDir {
+		return filepath.Join(marker, "checklist.db")
+	}
+	return marker
+}
+
+func openProject(marker string) (*sql.DB, func() error, error) {
+	if filepath.Base(marker) == projectFile {
+		return openChecklistFile(marker)
+	}
+	path := projectDBPath(marker)
+	if _, err := os.Stat(path); os.IsNotExist(err) && hasMarkdown(marker) {
+		return openMarkdownDir(marker)
+	}
+	return openSQLite(path)
+}
+
+// hasMarkdown reports whether dir holds markdown checklists.
+func hasMarkdown(dir string) bool {
+	files, _ := markdownFiles(dir)
+	return len(files) > 0
+}
+
+// openChecklistFile loads a CHECKLIST.md into an in-memory database. Each
+// change is written back to the file as it is made.
+func openChecklistFile(path string) (*sql.DB, func() error, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return openMarkdown(&markdownWorkspace{files: []*markdownFile{parseChecklistFile(path, string(text))}})
+}
+
+// parseChecklistFile splits a markdown file into one checklist per "# "
+// heading. Tasks before the first heading go into a checklist named
+// "Checklist".
+func parseChecklistFile(path, text string) *markdownFile {
+	f := &markdownFile{path: path, text: text}
+	lines := fileLines(text)
+
+	var bounds []int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# ") {
+			bounds = append(bounds, i)
+		}
+	}
+	bounds = append(bounds, len(lines))
+
+	if tasks := parseTaskBlocks(lines[:bounds[0]]); len(tasks) > 0 {
+		f.sections = append(f.sections, markdownSection{title: "Checklist", end: bounds[0], heading: -1, tasks: tasks})
+	}
+	for k, start := range bounds[:len(bounds)-1] {
+		end := bounds[k+1]
+		tasks := parseTaskBlocks(lines[start:end])
+		for i := range tasks {
+			tasks[i].start += start
+			tasks[i].end += start
+		}
+		f.sections = append(f.sections, markdownSection{
+			title:   strings.TrimSpace(strings.TrimPrefix(lines[start], "# ")),
+			start:   start,
+			end:     end,
+			heading: start,
+			tasks:   tasks,
+		})
+	}
+	return f
+}
+
+// writeFileAtomic replaces path by writing a temp file next to it and
+// renaming it into place, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if fi, err := os.Stat(path); err == nil {
+		f.Chmod(fi.Mode())
+	} else {
+		f.Chmod(0o644)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
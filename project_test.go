@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "repo", "cmd", "tool")
+	os.MkdirAll(nested, 0o755)
+	os.WriteFile(filepath.Join(root, "repo", projectFile), []byte("# Release\n"), 0o644)
+
+	marker, ok := findProject(nested)
+	if !ok || marker != filepath.Join(root, "repo", projectFile) {
+		t.Errorf("findProject(%q) = %q, %v; expected %q", nested, marker, ok, filepath.Join(root, "repo", projectFile))
+	}
+	if projectName(marker) != "repo" {
+		t.Errorf("projectName(%q) = %q; expected %q", marker, projectName(marker), "repo")
+	}
+
+	// A .chkmrk directory closer to the working directory wins.
+	os.Mkdir(filepath.Join(root, "repo", "cmd", projectDir), 0o755)
+	marker, ok = findProject(nested)
+	if !ok || marker != filepath.Join(root, "repo", "cmd", projectDir) {
+		t.Errorf("findProject(%q) = %q, %v; expected %q", nested, marker, ok, filepath.Join(root, "repo", "cmd", projectDir))
+	}
+
+	if marker, ok := findProject(root); ok {
+		t.Errorf("findProject(%q) = %q; expected no project", root, marker)
+	}
+}
+
+func TestChecklistFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), projectFile)
+	text := "# Release\n\n- [ ] Tag\n  run `git tag`\n\n  then push\n- [x] Test\n\n# Review\n\n- [ ] Read diff\n"
+	os.WriteFile(path, []byte(text), 0o644)
+
+	db, closeDB, err := openChecklistFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lists, _ := getChecklists(db)
+	if len(lists) != 2 || lists[0].Title != "Release" || lists[1].Title != "Review" {
+		t.Fatalf("getChecklists() = %v; expected Release and Review", lists)
+	}
+	item, _ := getItemById(db, 1)
+	if item.Note != "run `git tag`\n\nthen push" {
+		t.Errorf("item.Note = %q; expected %q", item.Note, "run `git tag`\n\nthen push")
+	}
+
+	updateItemCompleted(db, 3, true)
+	if err := closeDB(); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, _ := os.ReadFile(path)
+	expected := "# Release\n\n- [ ] Tag\n  run `git tag`\n\n  then push\n- [x] Test\n\n# Review\n\n- [x] Read diff\n"
+	if string(actual) != expected {
+		t.Errorf("CHECKLIST.md after close = %q; expected %q", actual, expected)
+	}
+}
+
+func TestChecklistFileKeepsProse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), projectFile)
+	text := "Release steps for the repo.\n\n# Release\n\n## Prep\n\nRead the notes first.\n\n- [ ] Tag\n  run `git tag`\n- [ ] Push\n\nSee [the wiki](https://example.com).\n\n# Review\n\n* [X] Read diff <!-- id:9 -->\n"
+	os.WriteFile(path, []byte(text), 0o644)
+
+	db, closeDB, err := openChecklistFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+
+	tests := []struct {
+		change   func() error
+		expected string
+	}{
+		{
+			func() error { return updateItemCompleted(db, 1, true) },
+			"Release steps for the repo.\n\n# Release\n\n## Prep\n\nRead the notes first.\n\n- [x] Tag\n  run `git tag`\n- [ ] Push\n\nSee [the wiki](https://example.com).\n\n# Review\n\n* [X] Read diff <!-- id:9 -->\n",
+		},
+		{
+			func() error { return addItem(db, "Announce", false, 2) },
+			"Release steps for the repo.\n\n# Release\n\n## Prep\n\nRead the notes first.\n\n- [x] Tag\n  run `git tag`\n- [ ] Push\n\nSee [the wiki](https://example.com).\n\n# Review\n\n* [X] Read diff <!-- id:9 -->\n- [ ] Announce\n",
+		},
+		{
+			func() error { return updateItemsCompleted(db, []int{3, 2}, false) },
+			"Release steps for the repo.\n\n# Release\n\n## Prep\n\nRead the notes first.\n\n- [x] Tag\n  run `git tag`\n- [ ] Push\n\nSee [the wiki](https://example.com).\n\n# Review\n\n* [ ] Read diff <!-- id:9 -->\n- [ ] Announce\n",
+		},
+		{
+			func() error { return updateItemNote(db, 2, "from main") },
+			"Release steps for the repo.\n\n# Release\n\n## Prep\n\nRead the notes first.\n\n- [x] Tag\n  run `git tag`\n- [ ] Push\n  from main\n\nSee [the wiki](https://example.com).\n\n# Review\n\n* [ ] Read diff <!-- id:9 -->\n- [ ] Announce\n",
+		},
+		{
+			func() error { return addChecklist(db, "Deploy") },
+			"Release steps for the repo.\n\n# Release\n\n## Prep\n\nRead the notes first.\n\n- [x] Tag\n  run `git tag`\n- [ ] Push\n  from main\n\nSee [the wiki](https://example.com).\n\n# Review\n\n* [ ] Read diff <!-- id:9 -->\n- [ ] Announce\n\n# Deploy\n",
+		},
+		{
+			func() error { return addItem(db, "Roll out", false, 3) },
+			"Release steps for the repo.\n\n# Release\n\n## Prep\n\nRead the notes first.\n\n- [x] Tag\n  run `git tag`\n- [ ] Push\n  from main\n\nSee [the wiki](https://example.com).\n\n# Review\n\n* [ ] Read diff <!-- id:9 -->\n- [ ] Announce\n\n# Deploy\n\n- [ ] Roll out\n",
+		},
+	}
+
+	// Each change is in the file as soon as it is made, before closing.
+	for index, test := range tests {
+		if err := test.change(); err != nil {
+			t.Fatalf("Test number %d -> change error = %v", index, err)
+		}
+		if actual, _ := os.ReadFile(path); string(actual) != test.expected {
+			t.Errorf("Test number %d -> CHECKLIST.md = %q; expected %q", index, actual, test.expected)
+		}
+	}
+
+	// A change made on disk meanwhile isn't overwritten.
+	edited := "# Release\n\n- [ ] Edited by hand\n"
+	os.WriteFile(path, []byte(edited), 0o644)
+	if err := updateItemCompleted(db, 2, true); err == nil {
+		t.Errorf("updateItemCompleted() after an edit on disk succeeded; expected an error")
+	}
+	if actual, _ := os.ReadFile(path); string(actual) != edited {
+		t.Errorf("CHECKLIST.md after a refused change = %q; expected %q", actual, edited)
+	}
+	if item, _ := getItemById(db, 2); item.Completed {
+		t.Errorf("the refused change was kept in the database")
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// commandIcon marks an item that carries a shell command.
+const commandIcon = "⚙"
+
+// maxRunOutput is how many lines of a run's output are kept, on screen and
+// in its history; the rest scroll off the top.
+const maxRunOutput = 200
+
+// runPaneLines is how many lines of output the run pane shows.
+const runPaneLines = 8
+
+var commandClearFlag bool
+
+var commandCmd = &cobra.Command{
+	Use:   "command <item-id> [<shell command>]",
+	Short: "Show or set the shell command of an item",
+	Long: `With only an item, print its command. With a command, set it; the
+words are joined with spaces and run by sh -c. In the TUI, r runs the
+command of the item under the cursor, streams its output and checks the
+item when it exits 0; a command that hasn't run here before is shown for
+confirmation first. Every run is kept in the item's history, which chkmrk
+show prints.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runCommand,
+}
+
+func init() {
+	commandCmd.Flags().BoolVar(&commandClearFlag, "clear", false, "remove the item's command")
+}
+
+// commandRun is a command started from the TUI. Its output reaches the
+// model as runOutputMsg, one line at a time, and its end as runDoneMsg.
+type commandRun struct {
+	itemID   int
+	title    string
+	command  string
+	started  time.Time
+	cmd      *exec.Cmd
+	output   []string
+	msgs     chan tea.Msg
+	done     bool
+	exitCode int
+}
+
+type runOutputMsg struct {
+	run  *commandRun
+	line string
+}
+
+type runDoneMsg struct {
+	run      *commandRun
+	exitCode int
+}
+
+// itemRun is one entry of an item's run history.
+type itemRun struct {
+	Command    string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	ExitCode   int
+	Output     string
+}
+
+// startRun runs the command of item with sh -c, its stdout and stderr
+// merged into one stream. Where the system has process groups it gets one
+// of its own, so stopping it stops whatever it started too.
+func startRun(item Item) (*commandRun, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("sh", "-c", item.Command)
+	setProcessGroup(cmd)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return nil, err
+	}
+	// The child holds its own copy; ours would keep the pipe from ending.
+	w.Close()
+
+	run := &commandRun{
+		itemID:  item.ID,
+		title:   item.Title,
+		command: item.Command,
+		started: time.Now(),
+		cmd:     cmd,
+		msgs:    make(chan tea.Msg, 64),
+	}
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			run.msgs <- runOutputMsg{run: run, line: scanner.Text()}
+		}
+		// A line too long to scan shouldn't leave the command stuck writing.
+		io.Copy(io.Discard, r)
+		r.Close()
+
+		cmd.Wait()
+		run.msgs <- runDoneMsg{run: run, exitCode: cmd.ProcessState.ExitCode()}
+	}()
+	return run, nil
+}
+
+// waitForRun delivers the next message of run.
+func waitForRun(run *commandRun) tea.Cmd {
+	return func() tea.Msg {
+		return <-run.msgs
+	}
+}
+
+// stop kills run and everything it started if it is still going. The exit
+// that follows is recorded like any other.
+func (run *commandRun) stop() {
+	if run == nil || run.done || run.cmd.Process == nil {
+		return
+	}
+	killProcessGroup(run.cmd)
+}
+
+// runCursorItem starts the command of the item under the cursor, or stops
+// the run in progress.
+func (m *model) runCursorItem() tea.Cmd {
+	if m.run != nil && !m.run.done {
+		m.run.stop()
+		return nil
+	}
+	item, ok := m.cursorItem()
+	if !ok {
+		return nil
+	}
+	if item.Command == "" {
+		m.err = fmt.Errorf("%q has no command; press %s to add one", item.Title, m.keys.Command.Help().Key)
+		return nil
+	}
+	if err := checkBlocked(m.db, []int{item.ID}); err != nil {
+		m.err = err
+		return nil
+	}
+
+	// Commands can arrive by sync, so one that hasn't run here yet is shown
+	// before it does.
+	runs, err := getRuns(m.db, item.ID)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	if len(runs) == 0 || runs[len(runs)-1].Command != item.Command {
+		m.openConfirm(fmt.Sprintf("Run the command of %q?", item.Title), []string{"$ " + item.Command}, func(m *model) tea.Cmd {
+			return m.beginRun(item)
+		})
+		return nil
+	}
+	return m.beginRun(item)
+}
+
+func (m *model) beginRun(item Item) tea.Cmd {
+	run, err := startRun(item)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	m.run = run
+	return waitForRun(run)
+}
+
+func SetCommandHandler(m *model) {
+	if item, ok := m.cursorItem(); ok {
+		if err := updateItemCommand(m.db, item.ID, strings.TrimSpace(m.textInput.Value())); err != nil {
+			m.err = err
+		}
+	}
+	m.editingCommand = false
+	m.reloadItems()
+}
+
+func RunOutputAction(m model, msg runOutputMsg) (tea.Model, tea.Cmd) {
+	run := msg.run
+	run.output = append(run.output, msg.line)
+	if len(run.output) > maxRunOutput {
+		run.output = run.output[len(run.output)-maxRunOutput:]
+	}
+	return m, waitForRun(run)
+}
+
+// RunDoneAction records a finished run in the item's history and checks
+// the item if the command succeeded.
+func RunDoneAction(m model, msg runDoneMsg) (tea.Model, tea.Cmd) {
+	run := msg.run
+	run.done = true
+	run.exitCode = msg.exitCode
+
+	if err := recordRun(m.db, run, time.Now()); err != nil {
+		m.err = err
+	} else if run.exitCode == 0 {
+		if err := checkBlocked(m.db, []int{run.itemID}); err != nil {
+			m.err = err
+		} else if err := updateItemCompleted(m.db, run.itemID, true); err != nil {
+			m.err = err
+		}
+	}
+	m.refresh()
+	return m, nil
+}
+
+// runPane shows the output of the last run under the open checklist.
+func runPane(m model, width int) string {
+	run := m.run
+	if run == nil || m.layout != ChecklistDetail {
+		return ""
+	}
+	status := "running…"
+	if run.done {
+		status = fmt.Sprintf("exit %d", run.exitCode)
+	}
+	s := fmt.Sprintf("\n  $ %s  (%s)\n", run.command, status)
+
+	lines := run.output[max(len(run.output)-runPaneLines, 0):]
+	for _, line := range lines {
+		s += "  " + cutLine(line, width-2) + "\n"
+	}
+	for i := len(lines); i < runPaneLines; i++ {
+		s += "\n"
+	}
+	return s
+}
+
+// cutLine shortens line to at most width runes.
+func cutLine(line string, width int) string {
+	runes := []rune(line)
+	if width < 1 || len(runes) <= width {
+		return line
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// recordRun adds a finished run to its item's history.
+func recordRun(db *sql.DB, run *commandRun, finished time.Time) error {
+	command, err := seal(db, run.command)
+	if err != nil {
+		return err
+	}
+	output, err := seal(db, strings.Join(run.output, "\n"))
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO runs (item_id, command, started_at, finished_at, exit_code, output) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err = db.Exec(query, run.itemID, command, run.started.Unix(), finished.Unix(), run.exitCode, output)
+	return err
+}
+
+// getRuns returns the run history of an item, oldest first.
+func getRuns(db *sql.DB, item_id int) ([]itemRun, error) {
+	query := `SELECT command, started_at, finished_at, exit_code, output FROM runs WHERE item_id = ? ORDER BY id`
+	rows, err := db.Query(query, item_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []itemRun
+	for rows.Next() {
+		var run itemRun
+		var started, finished int64
+		if err := rows.Scan(&run.Command, &started, &finished, &run.ExitCode, &run.Output); err != nil {
+			return nil, err
+		}
+		if run.Command, err = unseal(db, run.Command); err != nil {
+			return nil, err
+		}
+		if run.Output, err = unseal(db, run.Output); err != nil {
+			return nil, err
+		}
+		run.StartedAt, run.FinishedAt = time.Unix(started, 0), time.Unix(finished, 0)
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func runCommand(cmd *cobra.Command, args []string) error {
+	ids, err := parseIDs(args[:1])
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if commandClearFlag || len(args) > 1 {
+		if _, err := getItemById(db, ids[0]); err != nil {
+			return err
+		}
+		if err := updateItemCommand(db, ids[0], strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+	}
+
+	item, err := getItemById(db, ids[0])
+	if err != nil {
+		return err
+	}
+	if item.Command == "" {
+		fmt.Printf("Item %d has no command\n", ids[0])
+		return nil
+	}
+	fmt.Println(item.Command)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// finishRun feeds the messages of the run m started back into it until
+// the command exits.
+func finishRun(t *testing.T, m model, cmd tea.Cmd) model {
+	t.Helper()
+	for cmd != nil {
+		next, nextCmd := m.Update(cmd())
+		m, cmd = next.(model), nextCmd
+	}
+	if m.run == nil || !m.run.done {
+		t.Fatalf("the run didn't finish")
+	}
+	return m
+}
+
+func TestRunCommand(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+
+	tests := []struct {
+		command   string
+		exitCode  int
+		completed bool
+		output    string
+	}{
+		{"echo built; echo tagged >&2", 0, true, "built\ntagged"},
+		{"echo broken; exit 3", 3, false, "broken"},
+	}
+
+	for index, test := range tests {
+		addItem(db, test.command, false, 1)
+		id := index + 1
+		if err := updateItemCommand(db, id, test.command); err != nil {
+			t.Fatal(err)
+		}
+
+		m := initialModel(db, defaultKeyMap())
+		m.openChecklist()
+		m.setCursor(index)
+		next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+		m = next.(model)
+		// A command that never ran here is shown first.
+		if cmd != nil || !m.confirming || !strings.Contains(ConfirmView(m), "$ "+test.command) {
+			t.Fatalf("Test number %d -> r ran the command without showing it:\n%s", index, ConfirmView(m))
+		}
+		next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+		m = finishRun(t, next.(model), cmd)
+
+		if m.run.exitCode != test.exitCode || !strings.Contains(ChecklistDetailView(m), test.output[:5]) {
+			t.Errorf("Test number %d -> run exited %d showing:\n%s\nexpected exit %d", index, m.run.exitCode, ChecklistDetailView(m), test.exitCode)
+		}
+		if item, _ := getItemById(db, id); item.Completed != test.completed {
+			t.Errorf("Test number %d -> item completed = %v; expected %v", index, item.Completed, test.completed)
+		}
+		runs, err := getRuns(db, id)
+		if err != nil || len(runs) != 1 {
+			t.Fatalf("Test number %d -> getRuns() = %v, %v; expected one run", index, runs, err)
+		}
+		if runs[0].ExitCode != test.exitCode || runs[0].Command != test.command || runs[0].Output != test.output {
+			t.Errorf("Test number %d -> recorded %+v; expected exit %d with output %q", index, runs[0], test.exitCode, test.output)
+		}
+	}
+}
+
+func TestRunRefused(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addItem(db, "Test", false, 1)
+	addItem(db, "Tag", false, 1)
+	updateItemCommand(db, 2, "true")
+	setDependencies(db, 2, []int{1})
+
+	m := initialModel(db, defaultKeyMap())
+	m.openChecklist()
+	for index, expected := range []string{"has no command", "blocked by"} {
+		m.setCursor(index)
+		next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+		m = next.(model)
+		if cmd != nil || m.run != nil || !strings.Contains(ChecklistDetailView(m), expected) {
+			t.Errorf("Test number %d -> r started a run or didn't say %q:\n%s", index, expected, ChecklistDetailView(m))
+		}
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addItem(db, "Deploy", false, 1)
+	// The sleep outlives a killed sh and holds the output open.
+	updateItemCommand(db, 1, "sleep 30 & echo started; wait")
+
+	m := initialModel(db, defaultKeyMap())
+	m.openChecklist()
+	for _, key := range []string{"r", "y"} {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = next.(model)
+	}
+	if m.run == nil {
+		t.Fatalf("the command didn't start")
+	}
+	next, _ := m.Update(waitForRun(m.run)())
+	m = next.(model)
+	if len(m.run.output) != 1 || m.run.output[0] != "started" {
+		t.Fatalf("output = %q; expected the sleep started", m.run.output)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(model)
+
+	start := time.Now()
+	m = finishRun(t, m, waitForRun(m.run))
+	if elapsed := time.Since(start); elapsed > 10*time.Second || m.run.exitCode == 0 {
+		t.Errorf("stopped run ended after %v with exit %d; expected it killed at once", elapsed, m.run.exitCode)
+	}
+	if item, _ := getItemById(db, 1); item.Completed {
+		t.Errorf("a stopped run checked its item")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+
+const (
+	// defaultListHeight is used until the first tea.WindowSizeMsg arrives.
+	defaultListHeight = 20
+	// listChrome is the number of lines the views spend on the title,
+	// the page indicator and the help footer.
+	listChrome = 8
+	// inputChrome is the extra space taken while the text input is open.
+	inputChrome = 5
+	// splitChrome is what the two-pane layout spends on top of listChrome:
+	// the top and bottom borders of the panes around their titles.
+	splitChrome = 2
+)
+
+// listHeight returns how many list rows fit in the terminal.
+func (m model) listHeight() int {
+	if m.height == 0 {
+		return defaultListHeight
+	}
+	h := m.height - listChrome
+	if m.isSplit() {
+		h -= splitChrome
+	}
+	if m.showInput {
+		h -= inputChrome
+	}
+	if m.run != nil && m.layout == ChecklistDetail {
+		h -= runPaneLines + 3
+	}
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// clampOffset returns the first visible row of a window of size rows so
+// that cursor lies inside it, scrolling as little as possible.
+func clampOffset(cursor, offset, rows int) int {
+	if cursor < offset {
+		return cursor
+	}
+	if cursor >= offset+rows {
+		return cursor - rows + 1
+	}
+	return offset
+}
+
+// visibleRange returns the half-open range of rows to render.
+func visibleRange(cursor, offset, rows, total int) (int, int) {
+	start := clampOffset(cursor, offset, rows)
+	if start > total-rows {
+		start = total - rows
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + rows
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func pageIndicator(start, end, total int) string {
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("showing %d-%d of %d", start+1, end, total)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestVisibleRange(t *testing.T) {
+	tests := []struct {
+		cursor, offset, rows, total int
+		start, end                  int
+		indicator                   string
+	}{
+		{0, 0, 20, 140, 0, 20, "showing 1-20 of 140"},
+		{19, 0, 20, 140, 0, 20, "showing 1-20 of 140"},
+		{20, 0, 20, 140, 1, 21, "showing 2-21 of 140"},
+		{5, 10, 20, 140, 5, 25, "showing 6-25 of 140"},
+		{139, 0, 20, 140, 120, 140, "showing 121-140 of 140"},
+		{3, 0, 20, 5, 0, 5, "showing 1-5 of 5"},
+		{2, 8, 20, 5, 0, 5, "showing 1-5 of 5"},
+		{0, 0, 20, 0, 0, 0, ""},
+	}
+
+	for index, test := range tests {
+		start, end := visibleRange(test.cursor, test.offset, test.rows, test.total)
+		if start != test.start || end != test.end {
+			t.Errorf("Test number %d -> visibleRange(%d, %d, %d, %d) = (%d, %d); expected (%d, %d)", index, test.cursor, test.offset, test.rows, test.total, start, end, test.start, test.end)
+		}
+
+		indicator := pageIndicator(start, end, test.total)
+		if indicator != test.indicator {
+			t.Errorf("Test number %d -> pageIndicator(%d, %d, %d) = %q; expected %q", index, start, end, test.total, indicator, test.indicator)
+		}
+	}
+}
+
+func TestMoveCursor(t *testing.T) {
+	tests := []struct {
+		cursor, offset, delta    int
+		cursorAfter, offsetAfter int
+	}{
+		{0, 0, 1, 1, 0},
+		{0, 0, -1, 0, 0},
+		{0, 0, 20, 20, 1},
+		{20, 1, -20, 0, 0},
+		{10, 0, 200, 49, 30},
+		{49, 30, -200, 0, 0},
+	}
+
+	for index, test := range tests {
+		m := model{layout: ChecklistDetail, choices: make([]string, 50), height: 20 + listChrome}
+		m.cursor, m.offset = test.cursor, test.offset
+		m.moveCursor(test.delta)
+
+		if m.cursor != test.cursorAfter || m.offset != test.offsetAfter {
+			t.Errorf("Test number %d -> moveCursor(%d) from (%d, %d) = (%d, %d); expected (%d, %d)", index, test.delta, test.cursor, test.offset, m.cursor, m.offset, test.cursorAfter, test.offsetAfter)
+		}
+	}
+}
+
+func TestSplitViewHeight(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	for i := 0; i < 60; i++ {
+		addItem(db, fmt.Sprintf("Step %d", i), false, 1)
+	}
+
+	tests := []struct {
+		layout    Layout
+		height    int
+		showInput bool
+	}{
+		{Checklists, 20, false},
+		{ChecklistDetail, 20, false},
+		{ChecklistDetail, 40, false},
+		{ChecklistDetail, 30, true},
+	}
+
+	for index, test := range tests {
+		m := initialModel(db, defaultKeyMap())
+		m.width, m.height = 100, test.height
+		if test.layout == ChecklistDetail {
+			m.openChecklist()
+		}
+		m.loadPreview()
+		m.showInput = test.showInput
+
+		// The view ends in a newline, so it fits when it has fewer
+		// newlines than the terminal has lines.
+		if lines := strings.Count(m.View(), "\n"); lines >= test.height {
+			t.Errorf("Test number %d -> split view of layout %d is %d lines; expected it to fit in %d", index, test.layout, lines, test.height)
+		}
+	}
+}
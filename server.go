@@ -0,0 +1,549 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var serveAddrFlag string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the workspace as a JSON API over HTTP",
+	Args:  cobra.NoArgs,
+	RunE:  runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddrFlag, "addr", "127.0.0.1:8080", "address to listen on")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	return serveWorkspace(serveAddrFlag, newAPI)
+}
+
+// serveWorkspace serves the current workspace with the handler newHandler
+// builds until interrupted, then closes the database.
+func serveWorkspace(addr string, newHandler func(db *sql.DB) http.Handler) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: checkHost(addr, newHandler(db))}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	log.Printf("Serving on http://%s", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// checkHost refuses requests for a host name other than the one served on
+// or localhost, so a page can't rebind its own name to this server's
+// address to read it.
+func checkHost(addr string, next http.Handler) http.Handler {
+	served, _, _ := net.SplitHostPort(addr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if host != served && host != "localhost" && net.ParseIP(host) == nil {
+			http.Error(w, "unknown host", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin fails for a request another site's page sent: one with an
+// Origin that isn't this server.
+func checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+		return apiErrorf(http.StatusForbidden, "cross-origin request from %s", origin)
+	}
+	return nil
+}
+
+// guardWrites refuses changes a browser could send from another site's
+// page without asking: they must come from this server's origin and carry
+// a JSON body or an X-Requested-With header, either of which a cross-site
+// page can only send after a CORS preflight, which this server never
+// grants.
+func guardWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := checkOrigin(r); err != nil {
+			writeError(w, err)
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" && r.Header.Get("X-Requested-With") == "" {
+			writeError(w, apiErrorf(http.StatusForbidden, "send a JSON body or an X-Requested-With header"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The JSON shapes of the API.
+type (
+	apiItem struct {
+		ID        int    `json:"id"`
+		Title     string `json:"title"`
+		Completed bool   `json:"completed"`
+		Blocked   bool   `json:"blocked"`
+		Note      string `json:"note"`
+	}
+	apiChecklist struct {
+		ID    int       `json:"id"`
+		Title string    `json:"title"`
+		Items []apiItem `json:"items,omitempty"`
+	}
+)
+
+func toAPIItem(item Item) apiItem {
+	return apiItem{ID: item.ID, Title: item.Title, Completed: item.Completed, Blocked: item.Blocked && !item.Completed, Note: item.Note}
+}
+
+// api serves checklists and items as JSON:
+//
+//	GET    /checklists                 list checklists
+//	POST   /checklists                 create a checklist  {"title"}
+//	GET    /checklists/{id}            a checklist with its items
+//	POST   /checklists/{id}/items      add an item  {"title", "completed"}
+//	PUT    /checklists/{id}/order      reorder items  {"ids": [...]}
+//	GET    /items/{id}                 an item
+//	PATCH  /items/{id}                 update an item  {"completed"}
+//	POST   /items/{id}/toggle          toggle an item
+//	DELETE /items/{id}                 delete an item
+//
+// Checklists and items carry an ETag. Writes that send If-Match fail with
+// 412 Precondition Failed if the resource changed since it was read.
+// Writes must send JSON or an X-Requested-With header.
+type api struct {
+	db *sql.DB
+}
+
+func newAPI(db *sql.DB) http.Handler {
+	mux := http.NewServeMux()
+	a := api{db: db}
+	mux.HandleFunc("/checklists", a.checklists)
+	mux.HandleFunc("/checklists/", a.checklist)
+	mux.HandleFunc("/items/", a.item)
+	return guardWrites(mux)
+}
+
+// querier reads the database, outside a transaction or inside one.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// apiError is an error with the HTTP status to report it with.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e apiError) Error() string { return e.msg }
+
+func apiErrorf(status int, format string, args ...interface{}) error {
+	return apiError{status, fmt.Sprintf(format, args...)}
+}
+
+var errNotFound = apiErrorf(http.StatusNotFound, "not found")
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr apiError
+	if errors.As(err, &apiErr) {
+		status = apiErr.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func readJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return apiErrorf(http.StatusBadRequest, "invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// etag is a strong validator for the JSON form of v.
+func etag(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%x"`, sum[:8])
+}
+
+// checkIfMatch fails when the client sent an If-Match that isn't current.
+func checkIfMatch(r *http.Request, current string) error {
+	match := r.Header.Get("If-Match")
+	if match == "" || match == "*" || match == current {
+		return nil
+	}
+	return apiErrorf(http.StatusPreconditionFailed, "resource changed; current ETag is %s", current)
+}
+
+// writeIfMatch runs write in one transaction with the If-Match check
+// against the resource load reads, so nothing can change in between.
+func writeIfMatch(db *sql.DB, r *http.Request, load func(q querier) (interface{}, error), write func(tx *sql.Tx) error) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		current, err := load(tx)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, etag(current)); err != nil {
+			return err
+		}
+		return write(tx)
+	})
+}
+
+// writeResource writes v with its ETag, or 304 Not Modified for a GET whose
+// If-None-Match is current.
+func writeResource(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	tag := etag(v)
+	w.Header().Set("ETag", tag)
+	if r.Method == http.MethodGet && r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, status, v)
+}
+
+// pathID splits "/prefix/{id}/rest" into the ID and rest.
+func pathID(path, prefix string) (int, string, error) {
+	idText, rest, _ := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+	id, err := strconv.Atoi(idText)
+	if err != nil {
+		return 0, "", errNotFound
+	}
+	return id, rest, nil
+}
+
+func (a api) checklists(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		lists, err := getChecklists(a.db)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		out := []apiChecklist{}
+		for _, list := range lists {
+			out = append(out, apiChecklist{ID: list.ID, Title: list.Title})
+		}
+		writeResource(w, r, http.StatusOK, out)
+
+	case http.MethodPost:
+		var body struct {
+			Title string `json:"title"`
+		}
+		if err := readJSON(r, &body); err != nil {
+			writeError(w, err)
+			return
+		}
+		if strings.TrimSpace(body.Title) == "" {
+			writeError(w, apiErrorf(http.StatusBadRequest, "title is required"))
+			return
+		}
+		id, err := addChecklistFromTemplate(a.db, checklistTemplate{Title: body.Title})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		list, err := a.loadChecklist(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/checklists/%d", id))
+		writeResource(w, r, http.StatusCreated, list)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, apiErrorf(http.StatusMethodNotAllowed, "method not allowed"))
+	}
+}
+
+// itemColumns are the item columns of the API, in the order scanItem
+// reads them.
+const itemColumns = `id, title, completed, checklist_id, note, ` + blockedColumn
+
+func (a api) scanItem(scan func(dest ...interface{}) error) (Item, error) {
+	var item Item
+	if err := scan(&item.ID, &item.Title, &item.Completed, &item.ChecklistID, &item.Note, &item.Blocked); err != nil {
+		return Item{}, err
+	}
+	return item, unsealItem(a.db, &item)
+}
+
+func (a api) loadChecklist(id int) (apiChecklist, error) {
+	return a.readChecklist(a.db, id)
+}
+
+func (a api) readChecklist(q querier, id int) (apiChecklist, error) {
+	out := apiChecklist{ID: id, Items: []apiItem{}}
+	err := q.QueryRow(`SELECT title FROM checklists WHERE id = ? AND deleted_at IS NULL`, id).Scan(&out.Title)
+	if err == sql.ErrNoRows {
+		return apiChecklist{}, errNotFound
+	}
+	if err != nil {
+		return apiChecklist{}, err
+	}
+	if out.Title, err = unseal(a.db, out.Title); err != nil {
+		return apiChecklist{}, err
+	}
+
+	rows, err := q.Query(`SELECT `+itemColumns+` FROM items WHERE checklist_id = ? AND deleted_at IS NULL ORDER BY position, id`, id)
+	if err != nil {
+		return apiChecklist{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item, err := a.scanItem(rows.Scan)
+		if err != nil {
+			return apiChecklist{}, err
+		}
+		out.Items = append(out.Items, toAPIItem(item))
+	}
+	return out, rows.Err()
+}
+
+func (a api) readItem(q querier, id int) (Item, error) {
+	item, err := a.scanItem(q.QueryRow(`SELECT `+itemColumns+` FROM items WHERE id = ? AND deleted_at IS NULL`, id).Scan)
+	if err == sql.ErrNoRows {
+		return Item{}, errNotFound
+	}
+	return item, err
+}
+
+// checklistAt and itemAt load a resource for writeIfMatch.
+func (a api) checklistAt(id int) func(q querier) (interface{}, error) {
+	return func(q querier) (interface{}, error) {
+		return a.readChecklist(q, id)
+	}
+}
+
+func (a api) itemAt(id int) func(q querier) (interface{}, error) {
+	return func(q querier) (interface{}, error) {
+		item, err := a.readItem(q, id)
+		return toAPIItem(item), err
+	}
+}
+
+func (a api) checklist(w http.ResponseWriter, r *http.Request) {
+	if err := a.serveChecklist(w, r); err != nil {
+		writeError(w, err)
+	}
+}
+
+func (a api) serveChecklist(w http.ResponseWriter, r *http.Request) error {
+	id, rest, err := pathID(r.URL.Path, "/checklists/")
+	if err != nil {
+		return err
+	}
+	list, err := a.loadChecklist(id)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeResource(w, r, http.StatusOK, list)
+		return nil
+
+	case rest == "items" && r.Method == http.MethodPost:
+		var body struct {
+			Title     string `json:"title"`
+			Completed bool   `json:"completed"`
+		}
+		if err := readJSON(r, &body); err != nil {
+			return err
+		}
+		if strings.TrimSpace(body.Title) == "" {
+			return apiErrorf(http.StatusBadRequest, "title is required")
+		}
+		title, err := seal(a.db, body.Title)
+		if err != nil {
+			return err
+		}
+		err = writeIfMatch(a.db, r, a.checklistAt(id), func(tx *sql.Tx) error {
+			return insertItem(tx, title, body.Completed, id)
+		})
+		if err != nil {
+			return err
+		}
+
+	case rest == "order" && r.Method == http.MethodPut:
+		var body struct {
+			IDs []int `json:"ids"`
+		}
+		if err := readJSON(r, &body); err != nil {
+			return err
+		}
+		err := writeIfMatch(a.db, r, a.checklistAt(id), func(tx *sql.Tx) error {
+			if err := setItemOrder(tx, id, body.IDs); err != nil {
+				return apiErrorf(http.StatusBadRequest, "%v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+	case rest == "" || rest == "items" || rest == "order":
+		return apiErrorf(http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		return errNotFound
+	}
+
+	list, err = a.loadChecklist(id)
+	if err != nil {
+		return err
+	}
+	status := http.StatusOK
+	if rest == "items" {
+		status = http.StatusCreated
+	}
+	writeResource(w, r, status, list)
+	return nil
+}
+
+func (a api) item(w http.ResponseWriter, r *http.Request) {
+	if err := a.serveItem(w, r); err != nil {
+		writeError(w, err)
+	}
+}
+
+// checkCompletable refuses to check an item whose prerequisites aren't
+// done.
+func checkCompletable(db *sql.DB, id int, completed bool) error {
+	if !completed {
+		return nil
+	}
+	err := checkBlocked(db, []int{id})
+	if blocked, ok := err.(*blockedError); ok {
+		return apiErrorf(http.StatusConflict, "%s", blocked)
+	}
+	return err
+}
+
+func (a api) serveItem(w http.ResponseWriter, r *http.Request) error {
+	id, rest, err := pathID(r.URL.Path, "/items/")
+	if err != nil {
+		return err
+	}
+	item, err := a.readItem(a.db, id)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeResource(w, r, http.StatusOK, toAPIItem(item))
+		return nil
+
+	case rest == "" && r.Method == http.MethodPatch:
+		var body struct {
+			Completed *bool `json:"completed"`
+		}
+		if err := readJSON(r, &body); err != nil {
+			return err
+		}
+		if body.Completed != nil {
+			if err := checkCompletable(a.db, id, *body.Completed); err != nil {
+				return err
+			}
+		}
+		err := writeIfMatch(a.db, r, a.itemAt(id), func(tx *sql.Tx) error {
+			if body.Completed == nil {
+				return nil
+			}
+			return setItemCompleted(tx, id, *body.Completed)
+		})
+		if err != nil {
+			return err
+		}
+
+	case rest == "toggle" && r.Method == http.MethodPost:
+		if err := checkCompletable(a.db, id, !item.Completed); err != nil {
+			return err
+		}
+		err := writeIfMatch(a.db, r, a.itemAt(id), func(tx *sql.Tx) error {
+			current, err := a.readItem(tx, id)
+			if err != nil {
+				return err
+			}
+			if current.Completed != item.Completed {
+				return apiErrorf(http.StatusConflict, "item %d changed while toggling it", id)
+			}
+			return setItemCompleted(tx, id, !item.Completed)
+		})
+		if err != nil {
+			return err
+		}
+
+	case rest == "" && r.Method == http.MethodDelete:
+		err := writeIfMatch(a.db, r, a.itemAt(id), func(tx *sql.Tx) error {
+			return trashItem(tx, id)
+		})
+		if err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+
+	case rest == "" || rest == "toggle":
+		return apiErrorf(http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		return errNotFound
+	}
+
+	item, err = a.readItem(a.db, id)
+	if err != nil {
+		return err
+	}
+	writeResource(w, r, http.StatusOK, toAPIItem(item))
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addItem(db, "Tag", false, 1)
+	addItem(db, "Test", false, 1)
+	srv := httptest.NewServer(newAPI(db))
+	defer srv.Close()
+
+	do := func(method, path, body string, header ...string) *http.Response {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	list := do("GET", "/checklists/1", "")
+	tag := list.Header.Get("ETag")
+	if list.StatusCode != http.StatusOK || tag == "" {
+		t.Fatalf("GET /checklists/1 = %d with ETag %q", list.StatusCode, tag)
+	}
+
+	tests := []struct {
+		method, path, body string
+		header             []string
+		expected           int
+	}{
+		{"GET", "/checklists", "", nil, http.StatusOK},
+		{"GET", "/checklists/1", "", []string{"If-None-Match", tag}, http.StatusNotModified},
+		{"GET", "/checklists/9", "", nil, http.StatusNotFound},
+		{"POST", "/checklists", `{"title": "Review"}`, nil, http.StatusCreated},
+		{"POST", "/checklists", `{}`, nil, http.StatusBadRequest},
+		{"POST", "/checklists/1/items", `{"title": "Push"}`, []string{"If-Match", tag}, http.StatusCreated},
+		// The checklist changed, so the old ETag no longer matches.
+		{"PUT", "/checklists/1/order", `{"ids": [3, 2, 1]}`, []string{"If-Match", tag}, http.StatusPreconditionFailed},
+		{"PUT", "/checklists/1/order", `{"ids": [3, 2, 1]}`, nil, http.StatusOK},
+		{"PUT", "/checklists/1/order", `{"ids": [3, 2]}`, nil, http.StatusBadRequest},
+		{"POST", "/items/2/toggle", "", nil, http.StatusOK},
+		{"PATCH", "/items/1", `{"completed": true}`, nil, http.StatusOK},
+		{"PATCH", "/items/1", `{"completed": false}`, []string{"If-Match", `"stale"`}, http.StatusPreconditionFailed},
+		{"DELETE", "/items/1", "", nil, http.StatusNoContent},
+		{"GET", "/items/1", "", nil, http.StatusNotFound},
+		{"PUT", "/items/2", "", nil, http.StatusMethodNotAllowed},
+		// What a page on another site can send without a preflight.
+		{"POST", "/items/2/toggle", "", []string{"Content-Type", ""}, http.StatusForbidden},
+		{"POST", "/checklists", `{"title": "Pwned"}`, []string{"Content-Type", "text/plain"}, http.StatusForbidden},
+		{"POST", "/items/2/toggle", "", []string{"Origin", "https://evil.example"}, http.StatusForbidden},
+		{"POST", "/items/2/toggle", "", []string{"Content-Type", "", "X-Requested-With", "curl"}, http.StatusOK},
+	}
+
+	for index, test := range tests {
+		resp := do(test.method, test.path, test.body, test.header...)
+		if resp.StatusCode != test.expected {
+			t.Errorf("Test number %d -> %s %s = %d; expected %d", index, test.method, test.path, resp.StatusCode, test.expected)
+		}
+	}
+
+	var actual apiChecklist
+	json.NewDecoder(do("GET", "/checklists/1", "").Body).Decode(&actual)
+	expected := []apiItem{{ID: 3, Title: "Push"}, {ID: 2, Title: "Test"}}
+	if len(actual.Items) != 2 || actual.Items[0] != expected[0] || actual.Items[1] != expected[1] {
+		t.Errorf("checklist items = %v; expected %v", actual.Items, expected)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	handler := checkHost("127.0.0.1:8080", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		host     string
+		expected int
+	}{
+		{"127.0.0.1:8080", http.StatusOK},
+		{"localhost:8080", http.StatusOK},
+		{"[::1]:8080", http.StatusOK},
+		{"rebound.example:8080", http.StatusForbidden},
+	}
+	for index, test := range tests {
+		req := httptest.NewRequest("GET", "/checklists", nil)
+		req.Host = test.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expected {
+			t.Errorf("Test number %d -> GET with Host %s = %d; expected %d", index, test.host, rec.Code, test.expected)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// bundlePrefix starts every bundle, so receive can tell a bundle from a
+// file name and reject bundles from a future format.
+const bundlePrefix = "chkmrk1:"
+
+// maxBundleSize caps what a bundle may decompress to, far above any real
+// checklist, so a small bundle can't expand to fill memory.
+const maxBundleSize = 4 << 20
+
+var shareOutputFlag string
+
+var shareCmd = &cobra.Command{
+	Use:   "share <checklist>",
+	Short: "Print a checklist as a bundle string to paste to someone",
+	Long: `Print the checklist, its items and their notes as one compressed
+base64 line that chkmrk receive turns back into a checklist.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShare,
+}
+
+var receiveCmd = &cobra.Command{
+	Use:   "receive <bundle | file | ->",
+	Short: "Import a shared checklist bundle as a new checklist",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runReceive,
+}
+
+func init() {
+	shareCmd.Flags().StringVarP(&shareOutputFlag, "output", "o", "", "write the bundle to a file instead of stdout")
+}
+
+type (
+	bundle struct {
+		Title string       `json:"title"`
+		Items []bundleItem `json:"items"`
+	}
+	bundleItem struct {
+		Title     string `json:"title"`
+		Completed bool   `json:"completed,omitempty"`
+		Note      string `json:"note,omitempty"`
+	}
+)
+
+func encodeBundle(t checklistTemplate) (string, error) {
+	b := bundle{Title: t.Title, Items: []bundleItem{}}
+	for _, line := range t.Lines {
+		b.Items = append(b.Items, bundleItem{Title: line.Title, Completed: line.Completed, Note: line.Note})
+	}
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return bundlePrefix + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeBundle(text string) (checklistTemplate, error) {
+	text = strings.TrimSpace(text)
+	data, ok := strings.CutPrefix(text, bundlePrefix)
+	if !ok {
+		return checklistTemplate{}, fmt.Errorf("not a chkmrk bundle")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return checklistTemplate{}, fmt.Errorf("corrupt bundle: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return checklistTemplate{}, fmt.Errorf("corrupt bundle: %w", err)
+	}
+	plain, err := io.ReadAll(io.LimitReader(zr, maxBundleSize+1))
+	if err != nil {
+		return checklistTemplate{}, fmt.Errorf("corrupt bundle: %w", err)
+	}
+	if len(plain) > maxBundleSize {
+		return checklistTemplate{}, fmt.Errorf("bundle expands to more than %d MiB", maxBundleSize>>20)
+	}
+	var b bundle
+	if err := json.Unmarshal(plain, &b); err != nil {
+		return checklistTemplate{}, fmt.Errorf("corrupt bundle: %w", err)
+	}
+
+	if err := checkTitle(b.Title); err != nil {
+		return checklistTemplate{}, fmt.Errorf("bad bundle: %w", err)
+	}
+	t := checklistTemplate{Title: b.Title}
+	for _, item := range b.Items {
+		if err := checkTitle(item.Title); err != nil {
+			return checklistTemplate{}, fmt.Errorf("bad bundle: %w", err)
+		}
+		t.Lines = append(t.Lines, editLine{Title: item.Title, Completed: item.Completed, Note: item.Note})
+	}
+	return t, nil
+}
+
+// exportChecklist reads one checklist back as a template.
+func exportChecklist(db *sql.DB, list Checklist) (checklistTemplate, error) {
+	items, err := getItemsByChecklistId(db, list.ID)
+	if err != nil {
+		return checklistTemplate{}, err
+	}
+	t := checklistTemplate{Title: list.Title}
+	for _, item := range items {
+		t.Lines = append(t.Lines, editLine{Title: item.Title, Completed: item.Completed, Note: item.Note})
+	}
+	return t, nil
+}
+
+func runShare(cmd *cobra.Command, args []string) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	list, err := resolveChecklist(db, args[0])
+	if err != nil {
+		return err
+	}
+	t, err := exportChecklist(db, list)
+	if err != nil {
+		return err
+	}
+	text, err := encodeBundle(t)
+	if err != nil {
+		return err
+	}
+
+	if shareOutputFlag != "" {
+		return os.WriteFile(shareOutputFlag, []byte(text+"\n"), 0o644)
+	}
+	fmt.Println(text)
+	return nil
+}
+
+func runReceive(cmd *cobra.Command, args []string) error {
+	text := args[0]
+	switch {
+	case text == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	case !strings.HasPrefix(text, bundlePrefix):
+		data, err := os.ReadFile(text)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+
+	t, err := decodeBundle(text)
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if _, err := addChecklistFromTemplate(db, t); err != nil {
+		return err
+	}
+	fmt.Printf("Added %s (%s)\n", t.Title, itemCount(len(t.Lines)))
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addItem(db, "Tag", true, 1)
+	addItem(db, "Test", false, 1)
+	updateItemNote(db, 2, "run `go test ./...`\n\nthen push")
+
+	lists, _ := getChecklists(db)
+	original, _ := exportChecklist(db, lists[0])
+	text, err := encodeBundle(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, bundlePrefix) || strings.ContainsAny(text, " \n+/") {
+		t.Errorf("encodeBundle() = %q; expected one URL-safe line", text)
+	}
+
+	received, err := decodeBundle("  " + text + "\n")
+	if err != nil {
+		t.Fatalf("decodeBundle() error = %v", err)
+	}
+	if !reflect.DeepEqual(received, original) {
+		t.Errorf("decodeBundle() = %+v; expected %+v", received, original)
+	}
+
+	// A bomb: a note of zeros that compresses to a few KiB.
+	bomb, _ := encodeBundle(checklistTemplate{Title: "Bomb", Lines: []editLine{{Title: "x", Note: strings.Repeat("0", maxBundleSize)}}})
+
+	// Titles the TUI wouldn't take.
+	untitled, _ := encodeBundle(checklistTemplate{Title: " \t", Lines: []editLine{{Title: "Tag"}}})
+	blankItem, _ := encodeBundle(checklistTemplate{Title: "Release", Lines: []editLine{{Title: "  "}}})
+
+	tests := []string{"", "Release", bundlePrefix + "!!", bundlePrefix + "aGVsbG8", bomb, untitled, blankItem}
+	for index, test := range tests {
+		if _, err := decodeBundle(test); err == nil {
+			t.Errorf("Test number %d -> decodeBundle(%q) succeeded; expected an error", index, test)
+		}
+	}
+}
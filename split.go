@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// splitMinWidth is the narrowest terminal that gets the two-pane layout;
+// anything narrower falls back to the single-pane views.
+const splitMinWidth = 80
+
+var (
+	paneStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("240")).
+			Padding(0, 1)
+	focusedPaneStyle = paneStyle.BorderForeground(lipgloss.Color("62"))
+)
+
+func (m model) isSplit() bool {
+	return m.width >= splitMinWidth
+}
+
+// loadPreview loads the items of the checklist under the cursor so the right
+// pane can follow the left cursor while the checklists have focus.
+func (m *model) loadPreview() {
+	list, ok := m.cursorChecklist()
+	if !m.isSplit() || m.layout != Checklists || !ok {
+		m.preview = nil
+		return
+	}
+	m.preview, _ = getItemsByChecklistId(m.db, list.ID)
+}
+
+func SplitView(m model) string {
+	leftWidth := m.width / 3
+	rightWidth := m.width - leftWidth
+	rows := m.listHeight()
+
+	leftStyle, rightStyle := focusedPaneStyle, paneStyle
+	if m.layout == ChecklistDetail {
+		leftStyle, rightStyle = paneStyle, focusedPaneStyle
+	}
+
+	s := lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		leftStyle.Width(leftWidth-2).Render(checklistPane(m, rows)),
+		rightStyle.Width(rightWidth-2).Render(itemPane(m, rows)),
+	) + "\n"
+
+	if m.showInput {
+		s += fmt.Sprintf(
+			"\n%s\n\n%s\n\n%s",
+			m.inputPrompt(),
+			m.textInput.View(),
+			"(esc to quit)",
+		) + "\n"
+	}
+
+	s += errorLine(m)
+	s += "\n" + m.help.View(m.keys) + "\n"
+
+	return s
+}
+
+func checklistPane(m model, rows int) string {
+	s := "My Checklists" + workspaceLabel(m) + "\n\n"
+
+	focused := m.layout == Checklists
+	cursor, offset := m.listCursor, 0
+	if focused {
+		cursor, offset = m.cursor, m.offset
+	}
+
+	start, end := visibleRange(cursor, offset, rows, len(m.checklists))
+	for i := start; i < end; i++ {
+		marker := " "
+		if i == cursor {
+			marker = "*"
+			if focused {
+				marker = ">"
+			}
+		}
+		s += fmt.Sprintf("%s %s\n", marker, m.checklists[i].Title)
+	}
+	if len(m.checklists) == 0 {
+		s += noChecklistsMessage(m) + "\n"
+	}
+	s += "\n" + pageIndicator(start, end, len(m.checklists))
+
+	return s
+}
+
+func itemPane(m model, rows int) string {
+	if m.layout == Checklists {
+		list, ok := m.cursorChecklist()
+		if !ok {
+			return ""
+		}
+		s := list.Title + "\n\n"
+		start, end := visibleRange(0, 0, rows, len(m.preview))
+		for i := start; i < end; i++ {
+			checked := " "
+			if m.preview[i].Completed {
+				checked = "x"
+			}
+			title := m.preview[i].Title
+			if m.preview[i].Tags != "" {
+				title += " " + tagLabel(m.preview[i].Tags)
+			}
+			if m.preview[i].Blocked && !m.preview[i].Completed {
+				title = lockIcon + " " + title
+			}
+			s += fmt.Sprintf("  [%s] %s\n", checked, title)
+		}
+		if len(m.preview) == 0 {
+			s += "  No items yet.\n"
+		}
+		return s + "\n" + pageIndicator(start, end, len(m.preview))
+	}
+
+	s := m.activeListTitle + "\n\n"
+	start, end := visibleRange(m.cursor, m.offset, rows, len(m.choices))
+	for i := start; i < end; i++ {
+		cursor := " "
+		if m.isMarked(i) {
+			cursor = "+"
+		}
+		if m.cursor == i {
+			cursor = ">"
+		}
+
+		checked := " "
+		if _, ok := m.selected[i]; ok {
+			checked = "x"
+		}
+
+		s += fmt.Sprintf("%s [%s] %s\n", cursor, checked, m.itemLabel(i, m.choices[i]))
+	}
+	if len(m.choices) == 0 {
+		s += noItemsMessage(m) + "\n"
+	}
+	s += "\n" + pageIndicator(start, end, len(m.choices))
+	return s + notePreview(m, m.width-m.width/3-4) + runPane(m, m.width-m.width/3-4)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", sqliteDSN(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a separate database.
+	db.SetMaxOpenConns(1)
+	if err := initializeDB(db); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMoveAndCopyItems(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "From")
+	addChecklist(db, "To")
+	addItem(db, "Task 1", true, 1)
+	addItem(db, "Task 2", false, 1)
+	addItem(db, "Task 3", false, 1)
+
+	if err := moveItems(db, []int{1, 2}, 2); err != nil {
+		t.Fatalf("moveItems() error = %v", err)
+	}
+	if err := copyItems(db, []int{3}, 2); err != nil {
+		t.Fatalf("copyItems() error = %v", err)
+	}
+
+	tests := []struct {
+		checklist int
+		expected  []string
+	}{
+		{1, []string{"Task 3"}},
+		{2, []string{"Task 1", "Task 2", "Task 3"}},
+	}
+
+	for _, test := range tests {
+		items, err := getItemsByChecklistId(db, test.checklist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != len(test.expected) {
+			t.Fatalf("checklist %d has %d items; expected %d", test.checklist, len(items), len(test.expected))
+		}
+		for i, item := range items {
+			if item.Title != test.expected[i] {
+				t.Errorf("checklist %d item %d = %q; expected %q", test.checklist, i, item.Title, test.expected[i])
+			}
+		}
+	}
+
+	moved, _ := getItemById(db, 1)
+	if !moved.Completed {
+		t.Errorf("moved item lost its completed state")
+	}
+}
+
+// itemStates renders every item still out of the trash as its checklist,
+// check mark and title.
+func itemStates(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	lists, err := getChecklists(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []string
+	for _, list := range lists {
+		items, err := getItemsByChecklistId(db, list.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			checked := " "
+			if item.Completed {
+				checked = "x"
+			}
+			states = append(states, fmt.Sprintf("%s [%s] %s", list.Title, checked, item.Title))
+		}
+	}
+	return states
+}
+
+func TestBulkItemChanges(t *testing.T) {
+	untouched := []string{"From [x] Task 1", "From [ ] Task 2", "From [ ] Task 3"}
+
+	tests := []struct {
+		change   func(db *sql.DB) error
+		fails    bool
+		expected []string
+	}{
+		{
+			func(db *sql.DB) error { return updateItemsCompleted(db, []int{2, 3}, true) },
+			false,
+			[]string{"From [x] Task 1", "From [x] Task 2", "From [x] Task 3"},
+		},
+		{
+			func(db *sql.DB) error { return updateItemsCompleted(db, []int{1}, false) },
+			false,
+			[]string{"From [ ] Task 1", "From [ ] Task 2", "From [ ] Task 3"},
+		},
+		{
+			func(db *sql.DB) error { return deleteItems(db, []int{1, 3}) },
+			false,
+			[]string{"From [ ] Task 2"},
+		},
+		{
+			func(db *sql.DB) error { return moveItems(db, []int{3, 1}, 2) },
+			false,
+			[]string{"From [ ] Task 2", "To [ ] Task 3", "To [x] Task 1"},
+		},
+		// One bad ID rolls back the whole batch, wherever it is.
+		{func(db *sql.DB) error { return updateItemsCompleted(db, []int{2, 99}, true) }, true, untouched},
+		{func(db *sql.DB) error { return deleteItems(db, []int{1, 99, 3}) }, true, untouched},
+		{func(db *sql.DB) error { return moveItems(db, []int{1, 2, 99}, 2) }, true, untouched},
+		{func(db *sql.DB) error { return copyItems(db, []int{99, 1}, 2) }, true, untouched},
+		// Items in the trash count as gone.
+		{
+			func(db *sql.DB) error {
+				deleteItem(db, 2)
+				return updateItemsCompleted(db, []int{1, 2}, false)
+			},
+			true,
+			[]string{"From [x] Task 1", "From [ ] Task 3"},
+		},
+	}
+
+	for index, test := range tests {
+		db := newTestDB(t)
+		addChecklist(db, "From")
+		addChecklist(db, "To")
+		addItem(db, "Task 1", true, 1)
+		addItem(db, "Task 2", false, 1)
+		addItem(db, "Task 3", false, 1)
+
+		if err := test.change(db); (err != nil) != test.fails {
+			t.Errorf("Test number %d -> error = %v; expected failure %v", index, err, test.fails)
+		}
+		if actual := itemStates(t, db); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test number %d -> items = %q; expected %q", index, actual, test.expected)
+		}
+	}
+}
+
+func TestInitializeDBAddsNoteColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", sqliteDSN(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// The tables as they were created before notes existed.
+	_, err = db.Exec(`
+	CREATE TABLE checklists (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL
+	);
+	CREATE TABLE items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		completed BOOLEAN NOT NULL,
+		checklist_id INTEGER
+	);
+	INSERT INTO checklists (title) VALUES ('List 1');
+	INSERT INTO items (title, completed, checklist_id) VALUES ('Task 1', 0, 1);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := initializeDB(db); err != nil {
+		t.Fatalf("initializeDB() error = %v", err)
+	}
+	if err := initializeDB(db); err != nil {
+		t.Fatalf("second initializeDB() error = %v", err)
+	}
+
+	if err := updateItemNote(db, 1, "run `make release`"); err != nil {
+		t.Fatal(err)
+	}
+	item, err := getItemById(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Note != "run `make release`" {
+		t.Errorf("item.Note = %q; expected %q", item.Note, "run `make release`")
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+
+	if err := addItem(db, "Tag", false, 1); err != nil {
+		t.Errorf("addItem() to an existing checklist error = %v", err)
+	}
+	if err := addItem(db, "Orphan", false, 2); err == nil {
+		t.Errorf("addItem() to a missing checklist succeeded; expected a foreign key error")
+	}
+	if err := moveItems(db, []int{1}, 2); err == nil {
+		t.Errorf("moveItems() to a missing checklist succeeded; expected a foreign key error")
+	}
+}
+
+// TestConcurrentWrites runs writers on two connection pools to one file,
+// the way a TUI and CLI commands share a workspace.
+func TestConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checklist.db")
+	var pools []*sql.DB
+	for i := 0; i < 2; i++ {
+		db, closeDB, err := openSQLite(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeDB()
+		pools = append(pools, db)
+	}
+	addChecklist(pools[0], "Stress")
+
+	const workers, writes = 16, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*writes*2)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(db *sql.DB, w int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				if err := addItem(db, fmt.Sprintf("Task %d-%d", w, i), false, 1); err != nil {
+					errs <- err
+				}
+				if err := updateItemCompleted(db, w*writes+i+1, i%2 == 0); err != nil {
+					errs <- err
+				}
+			}
+		}(pools[w%2], w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent write error = %v", err)
+	}
+	items, _ := getItemsByChecklistId(pools[1], 1)
+	if len(items) != workers*writes {
+		t.Errorf("len(items) = %d; expected %d", len(items), workers*writes)
+	}
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <dir>",
+	Short: "Exchange changes with other machines through a shared folder",
+	Long: `Every change to a checklist or item is recorded as an operation in the
+database. sync writes this machine's operations to <dir>/<device>.jsonl and
+applies the operations other machines wrote there, so any folder the
+machines share (a synced drive, a network mount) keeps them in step.
+
+Edits to the same field on two machines resolve to the later one, and
+moving to and restoring from the trash count as edits. An item purged
+from the trash stays deleted.
+
+Encrypted machines share one key through <dir>/key.json. The first machine
+to sync sets it; a plain database takes it on, asking for the passphrase;
+a database encrypted under another key is refused until it is decrypted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSync,
+}
+
+// op is one change: a field of a checklist or item set to value, or the
+// item created or deleted. ts is a hybrid logical clock in milliseconds:
+// the wall clock, but always after every op the database has seen, so an
+// edit made after syncing wins over the edits it saw.
+type op struct {
+	ID     string `json:"id"`
+	Device string `json:"device"`
+	TS     int64  `json:"ts"`
+	Kind   string `json:"kind"`
+	UUID   string `json:"uuid"`
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+}
+
+// after reports whether o was written after the clock (ts, device). Ties
+// on ts go to the greater device so every machine picks the same winner.
+func (o op) after(ts int64, device string) bool {
+	return o.TS > ts || o.TS == ts && o.Device > device
+}
+
+// opClock is the SQL for the next local op's ts.
+const opClock = `MAX(CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER), (SELECT COALESCE(MAX(ts), 0) + 1 FROM ops))`
+
+// logOp is the SQL that records a local op from a trigger. Ops applied from
+// other machines are logged as they arrive, so triggers skip them.
+func logOp(kind, uuid, field, value string) string {
+	return fmt.Sprintf(`
+		INSERT INTO ops (id, device, ts, kind, uuid, field, value)
+		SELECT lower(hex(randomblob(16))), device, %s, '%s', %s, '%s', %s
+		FROM sync_state WHERE applying = 0;`, opClock, kind, uuid, field, value)
+}
+
+// initializeSync adds UUIDs to checklists and items and the triggers that
+// log every change to them.
+func initializeSync(tx *sql.Tx) error {
+	if err := addColumn(tx, "checklists", "uuid", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumn(tx, "items", "uuid", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	newUUID := `lower(hex(randomblob(16)))`
+	itemUUID := `(SELECT uuid FROM items WHERE id = NEW.id)`
+	checklistUUID := `(SELECT uuid FROM checklists WHERE id = NEW.checklist_id)`
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS ops (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		id TEXT NOT NULL UNIQUE,
+		device TEXT NOT NULL,
+		ts INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		uuid TEXT NOT NULL,
+		field TEXT NOT NULL,
+		value TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS ops_uuid ON ops (uuid, field);
+
+	CREATE TABLE IF NOT EXISTS clocks (
+		uuid TEXT NOT NULL,
+		field TEXT NOT NULL,
+		ts INTEGER NOT NULL,
+		device TEXT NOT NULL,
+		PRIMARY KEY (uuid, field)
+	);
+
+	CREATE TABLE IF NOT EXISTS sync_state (
+		device TEXT NOT NULL,
+		applying INTEGER NOT NULL DEFAULT 0
+	);
+	INSERT INTO sync_state (device)
+	SELECT lower(hex(randomblob(8))) WHERE NOT EXISTS (SELECT 1 FROM sync_state);
+
+	CREATE TRIGGER IF NOT EXISTS ops_clock AFTER INSERT ON ops
+	WHEN NEW.device = (SELECT device FROM sync_state)
+	BEGIN
+		INSERT OR REPLACE INTO clocks (uuid, field, ts, device) VALUES (NEW.uuid, NEW.field, NEW.ts, NEW.device);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS checklists_insert AFTER INSERT ON checklists
+	BEGIN
+		UPDATE checklists SET uuid = ` + newUUID + ` WHERE id = NEW.id AND uuid = '';` +
+		logOp("checklist", `(SELECT uuid FROM checklists WHERE id = NEW.id)`, "create", "NEW.title") + `
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS checklists_delete AFTER DELETE ON checklists
+	BEGIN` + logOp("checklist", "OLD.uuid", "delete", "''") + `
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS checklists_title AFTER UPDATE OF title ON checklists
+	WHEN NEW.title IS NOT OLD.title
+	BEGIN` + logOp("checklist", "NEW.uuid", "title", "NEW.title") + `
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS items_insert AFTER INSERT ON items
+	BEGIN
+		UPDATE items SET uuid = ` + newUUID + ` WHERE id = NEW.id AND uuid = '';` +
+		logOp("item", itemUUID, "create", `json_object(
+			'checklist', `+checklistUUID+`, 'title', NEW.title, 'completed', NEW.completed,
+			'note', NEW.note, 'position', NEW.position)`) + `
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS items_checklist AFTER UPDATE OF checklist_id ON items
+	WHEN NEW.checklist_id IS NOT OLD.checklist_id
+	BEGIN` + logOp("item", "NEW.uuid", "checklist", checklistUUID) + `
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS items_delete AFTER DELETE ON items
+	BEGIN` + logOp("item", "OLD.uuid", "delete", "''") + `
+	END;
+	`
+	// Trashing and restoring sync like any other field, with '' for
+	// restored.
+	for _, table := range []string{"checklists", "items"} {
+		schema += `
+	CREATE TRIGGER IF NOT EXISTS ` + table + `_deleted_at AFTER UPDATE OF deleted_at ON ` + table + `
+	WHEN NEW.deleted_at IS NOT OLD.deleted_at
+	BEGIN` + logOp(strings.TrimSuffix(table, "s"), "NEW.uuid", "deleted_at", "COALESCE(NEW.deleted_at, '')") + `
+	END;
+	`
+	}
+	for _, field := range itemFields {
+		schema += `
+	CREATE TRIGGER IF NOT EXISTS items_` + field + ` AFTER UPDATE OF ` + field + ` ON items
+	WHEN NEW.` + field + ` IS NOT OLD.` + field + `
+	BEGIN` + logOp("item", "NEW.uuid", field, "NEW."+field) + `
+	END;
+	`
+	}
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	// Rows from before sync existed get a UUID and a create op, so the
+	// first sync sends them.
+	_, err := tx.Exec(`
+	UPDATE checklists SET uuid = ` + newUUID + ` WHERE uuid = '';
+	UPDATE items SET uuid = ` + newUUID + ` WHERE uuid = '';
+
+	INSERT INTO ops (id, device, ts, kind, uuid, field, value)
+	SELECT ` + newUUID + `, (SELECT device FROM sync_state), ` + opClock + `, 'checklist', uuid, 'create', title
+	FROM checklists WHERE uuid NOT IN (SELECT uuid FROM ops WHERE field = 'create');
+
+	INSERT INTO ops (id, device, ts, kind, uuid, field, value)
+	SELECT ` + newUUID + `, (SELECT device FROM sync_state), ` + opClock + `, 'item', i.uuid, 'create', json_object(
+		'checklist', c.uuid, 'title', i.title, 'completed', i.completed, 'note', i.note, 'position', i.position)
+	FROM items i JOIN checklists c ON c.id = i.checklist_id
+	WHERE i.uuid NOT IN (SELECT uuid FROM ops WHERE field = 'create');`)
+	return err
+}
+
+// itemFields are the item columns synced as plain values.
+var itemFields = []string{"title", "completed", "note", "position", "depends_on", "command", "tags"}
+
+func localDevice(db *sql.DB) (string, error) {
+	var device string
+	err := db.QueryRow(`SELECT device FROM sync_state`).Scan(&device)
+	return device, err
+}
+
+// getOps returns the ops recorded on device in the order they were made.
+func getOps(db *sql.DB, device string) ([]op, error) {
+	rows, err := db.Query(`SELECT id, device, ts, kind, uuid, field, value FROM ops WHERE device = ? ORDER BY seq`, device)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ops []op
+	for rows.Next() {
+		var o op
+		if err := rows.Scan(&o.ID, &o.Device, &o.TS, &o.Kind, &o.UUID, &o.Field, &o.Value); err != nil {
+			return nil, err
+		}
+		ops = append(ops, o)
+	}
+	return ops, rows.Err()
+}
+
+// applyOps merges ops from other machines and returns how many were new.
+// Creates go first so edits to an item never arrive before the item; the
+// rest apply in clock order, each only if it is later than the field's
+// current value.
+func applyOps(db *sql.DB, ops []op) (int, error) {
+	var applied int
+	err := withTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE sync_state SET applying = 1`); err != nil {
+			return err
+		}
+
+		var fresh []op
+		for _, o := range ops {
+			result, err := tx.Exec(`INSERT OR IGNORE INTO ops (id, device, ts, kind, uuid, field, value) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				o.ID, o.Device, o.TS, o.Kind, o.UUID, o.Field, o.Value)
+			if err != nil {
+				return err
+			}
+			if n, _ := result.RowsAffected(); n == 1 {
+				fresh = append(fresh, o)
+			}
+		}
+		sort.SliceStable(fresh, func(i, j int) bool {
+			a, b := fresh[i], fresh[j]
+			if ra, rb := createRank(a), createRank(b); ra != rb {
+				return ra < rb
+			}
+			return b.after(a.TS, a.Device) || a.TS == b.TS && a.Device == b.Device && a.ID < b.ID
+		})
+
+		for _, o := range fresh {
+			if err := applyOp(tx, o); err != nil {
+				return fmt.Errorf("applying op %s: %w", o.ID, err)
+			}
+		}
+		applied = len(fresh)
+
+		_, err := tx.Exec(`UPDATE sync_state SET applying = 0`)
+		return err
+	})
+	return applied, err
+}
+
+// createRank orders checklist creates, then item creates, then edits.
+func createRank(o op) int {
+	switch {
+	case o.Field == "create" && o.Kind == "checklist":
+		return 0
+	case o.Field == "create":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// claimClock records o as the latest write to its field and reports
+// whether it was later than what was there.
+func claimClock(tx *sql.Tx, o op, field string) (bool, error) {
+	var ts int64
+	var device string
+	err := tx.QueryRow(`SELECT ts, device FROM clocks WHERE uuid = ? AND field = ?`, o.UUID, field).Scan(&ts, &device)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if err == nil && !o.after(ts, device) {
+		return false, nil
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO clocks (uuid, field, ts, device) VALUES (?, ?, ?, ?)`, o.UUID, field, o.TS, o.Device)
+	return true, err
+}
+
+func isDeleted(tx *sql.Tx, uuid string) (bool, error) {
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM clocks WHERE uuid = ? AND field = 'delete'`, uuid).Scan(&n)
+	return n > 0, err
+}
+
+func checklistIDByUUID(tx *sql.Tx, uuid string) (int, error) {
+	var id int
+	err := tx.QueryRow(`SELECT id FROM checklists WHERE uuid = ?`, uuid).Scan(&id)
+	return id, err
+}
+
+func applyOp(tx *sql.Tx, o op) error {
+	if o.Kind == "checklist" {
+		switch o.Field {
+		case "create":
+			if _, err := checklistIDByUUID(tx, o.UUID); err != sql.ErrNoRows {
+				return err
+			}
+			if deleted, err := isDeleted(tx, o.UUID); deleted || err != nil {
+				return err
+			}
+			if _, err := claimClock(tx, o, "title"); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO checklists (title, uuid) VALUES (?, ?)`, o.Value, o.UUID)
+			return err
+		case "title":
+			if later, err := claimClock(tx, o, o.Field); !later || err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE checklists SET title = ? WHERE uuid = ?`, o.Value, o.UUID)
+			return err
+		case "deleted_at":
+			if later, err := claimClock(tx, o, o.Field); !later || err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE checklists SET deleted_at = NULLIF(?, '') WHERE uuid = ?`, o.Value, o.UUID)
+			return err
+		case "delete":
+			// Purged for good, with its items.
+			if _, err := claimClock(tx, o, o.Field); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`DELETE FROM items WHERE checklist_id IN (SELECT id FROM checklists WHERE uuid = ?)`, o.UUID); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DELETE FROM checklists WHERE uuid = ?`, o.UUID)
+			return err
+		}
+		return nil
+	}
+
+	if deleted, err := isDeleted(tx, o.UUID); deleted || err != nil {
+		return err
+	}
+
+	switch o.Field {
+	case "create":
+		var v struct {
+			Checklist string `json:"checklist"`
+			Title     string `json:"title"`
+			Completed int    `json:"completed"`
+			Note      string `json:"note"`
+			Position  int    `json:"position"`
+		}
+		if err := json.Unmarshal([]byte(o.Value), &v); err != nil {
+			return err
+		}
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM items WHERE uuid = ?`, o.UUID).Scan(&exists); err != nil || exists > 0 {
+			return err
+		}
+		checklistID, err := checklistIDByUUID(tx, v.Checklist)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, field := range append(itemFields, "checklist") {
+			if _, err := claimClock(tx, o, field); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(`INSERT INTO items (title, completed, checklist_id, note, position, uuid) VALUES (?, ?, ?, ?, ?, ?)`,
+			v.Title, v.Completed != 0, checklistID, v.Note, v.Position, o.UUID)
+		return err
+
+	case "delete":
+		if _, err := claimClock(tx, o, o.Field); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM items WHERE uuid = ?`, o.UUID)
+		return err
+
+	case "checklist":
+		checklistID, err := checklistIDByUUID(tx, o.Value)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if later, err := claimClock(tx, o, o.Field); !later || err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE items SET checklist_id = ? WHERE uuid = ?`, checklistID, o.UUID)
+		return err
+
+	case "deleted_at":
+		if later, err := claimClock(tx, o, o.Field); !later || err != nil {
+			return err
+		}
+		_, err := tx.Exec(`UPDATE items SET deleted_at = NULLIF(?, '') WHERE uuid = ?`, o.Value, o.UUID)
+		return err
+	}
+
+	for _, field := range itemFields {
+		if o.Field != field {
+			continue
+		}
+		if later, err := claimClock(tx, o, o.Field); !later || err != nil {
+			return err
+		}
+		// The column affinity turns "1" back into a number for completed
+		// and position.
+		_, err := tx.Exec(fmt.Sprintf(`UPDATE items SET %s = ? WHERE uuid = ?`, field), o.Value, o.UUID)
+		return err
+	}
+	return nil
+}
+
+// syncDir writes this machine's ops to dir and applies everyone else's.
+// It returns the number of ops written and applied.
+func syncDir(db *sql.DB, dir string) (int, int, error) {
+	device, err := localDevice(db)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var others []string
+	for _, path := range files {
+		if strings.TrimSuffix(filepath.Base(path), ".jsonl") != device {
+			others = append(others, path)
+		}
+	}
+	if err := shareKey(db, dir, len(others) > 0); err != nil {
+		return 0, 0, err
+	}
+
+	var remote []op
+	for _, path := range others {
+		ops, err := readOps(path)
+		if err != nil {
+			return 0, 0, err
+		}
+		remote = append(remote, ops...)
+	}
+	applied, err := applyOps(db, remote)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	local, err := getOps(db, device)
+	if err != nil {
+		return 0, applied, err
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, o := range local {
+		enc.Encode(o)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, applied, err
+	}
+	return len(local), applied, writeFileAtomic(filepath.Join(dir, device+".jsonl"), []byte(b.String()))
+}
+
+// syncKeyFile names the file of a sync folder holding the key its ops are
+// sealed under. Without it they are plain text.
+const syncKeyFile = "key.json"
+
+// shareKey makes db use the key of the machines syncing through dir, so
+// each can read what the others write. With no others yet, dir takes the
+// key of db.
+func shareKey(db *sql.DB, dir string, others bool) error {
+	local, err := getKey(db)
+	if err != nil {
+		return err
+	}
+	shared, err := readSyncKey(dir)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case local.same(shared):
+		return nil
+	case !others:
+		return writeSyncKey(dir, local)
+	case local == nil:
+		passphrase, err := readPassphrase()
+		if err != nil {
+			return err
+		}
+		aead, err := shared.open(passphrase)
+		if err != nil {
+			return err
+		}
+		return rekeyTo(db, shared, aead)
+	case shared == nil:
+		return fmt.Errorf("the other machines syncing through %s don't encrypt; decrypt this database or sync through a new folder", dir)
+	default:
+		return fmt.Errorf("the machines syncing through %s use another key; decrypt this database and sync again to take theirs on", dir)
+	}
+}
+
+func readSyncKey(dir string) (*dbKey, error) {
+	data, err := os.ReadFile(filepath.Join(dir, syncKeyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var k dbKey
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, fmt.Errorf("%s: %w", syncKeyFile, err)
+	}
+	return &k, nil
+}
+
+// writeSyncKey records k as the key of dir, or removes it if k is nil.
+func writeSyncKey(dir string, k *dbKey) error {
+	path := filepath.Join(dir, syncKeyFile)
+	if k == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func readOps(path string) ([]op, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []op
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var o op
+		if err := json.Unmarshal(scanner.Bytes(), &o); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		ops = append(ops, o)
+	}
+	return ops, scanner.Err()
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if file, err := dbFile(db); err != nil || file == "" {
+		return fmt.Errorf("sync needs a workspace kept in a SQLite database")
+	}
+
+	sent, applied, err := syncDir(db, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Shared %d changes, applied %d new changes from other machines\n", sent, applied)
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syncState renders every checklist and its items for comparing machines.
+func syncState(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	lists, err := getChecklists(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state []string
+	for _, checklist := range lists {
+		list, err := exportChecklist(db, checklist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state = append(state, "# "+list.Title)
+		for _, line := range list.Lines {
+			checked := " "
+			if line.Completed {
+				checked = "x"
+			}
+			state = append(state, "["+checked+"] "+line.Title+" "+line.Note)
+		}
+	}
+	return state
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	laptop, workstation := newTestDB(t), newTestDB(t)
+	sync := func(db *sql.DB) {
+		t.Helper()
+		if _, _, err := syncDir(db, dir); err != nil {
+			t.Fatalf("syncDir() error = %v", err)
+		}
+	}
+
+	addChecklist(laptop, "Release")
+	addItem(laptop, "Tag", false, 1)
+	addItem(laptop, "Test", false, 1)
+	addItem(laptop, "Push", false, 1)
+	sync(laptop)
+	sync(workstation)
+
+	if actual := syncState(t, workstation); !reflect.DeepEqual(actual, syncState(t, laptop)) {
+		t.Fatalf("workstation after first sync = %v; expected %v", actual, syncState(t, laptop))
+	}
+
+	// Both machines edit before syncing again. The workstation's rename of
+	// Tag comes later, so it wins; other fields merge independently.
+	laptop.Exec(`UPDATE items SET title = 'Tag v1' WHERE title = 'Tag'`)
+	updateItemCompleted(laptop, 1, true)
+	deleteItem(laptop, 3)
+	time.Sleep(10 * time.Millisecond)
+	workstation.Exec(`UPDATE items SET title = 'Tag v2' WHERE title = 'Tag'`)
+	updateItemNote(workstation, 2, "run the suite")
+	updateItemNote(workstation, 3, "edit of a deleted item")
+	addItem(workstation, "Announce", false, 1)
+
+	sync(laptop)
+	sync(workstation)
+	sync(laptop)
+
+	expected := []string{"# Release", "[x] Tag v2 ", "[ ] Test run the suite", "[ ] Announce "}
+	for index, db := range []*sql.DB{laptop, workstation} {
+		if actual := syncState(t, db); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Test number %d -> state after sync = %v; expected %v", index, actual, expected)
+		}
+	}
+
+	// Syncing again changes nothing.
+	if _, applied, _ := syncDir(workstation, dir); applied != 0 {
+		t.Errorf("second sync applied %d ops; expected 0", applied)
+	}
+
+	// Moving to the trash and restoring sync like edits.
+	deleteChecklist(workstation, 1)
+	sync(workstation)
+	sync(laptop)
+	trash, _ := getTrash(laptop)
+	if len(trash) != 1 || trash[0].Title != "Release" {
+		t.Fatalf("laptop trash after sync = %v; expected Release", trash)
+	}
+	restoreTrashEntry(laptop, trash[0])
+	sync(laptop)
+	sync(workstation)
+	if actual := syncState(t, workstation); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("workstation after restore = %v; expected %v", actual, expected)
+	}
+
+	// Purging does too, and a stale copy doesn't bring the checklist back.
+	deleteChecklist(laptop, 1)
+	trash, _ = getTrash(laptop)
+	purgeTrashEntry(laptop, trash[0])
+	sync(laptop)
+	sync(workstation)
+	sync(laptop)
+	for index, db := range []*sql.DB{laptop, workstation} {
+		lists, _ := getChecklists(db)
+		trash, _ := getTrash(db)
+		if len(lists) != 0 || len(trash) != 0 {
+			t.Errorf("Test number %d -> after purge: checklists %v, trash %v; expected none", index, lists, trash)
+		}
+	}
+}
+
+func TestSyncEncrypted(t *testing.T) {
+	defer func(n int) { kdfIterations = n }(kdfIterations)
+	kdfIterations = 1000
+	allowPrompt = false
+	defer func() { allowPrompt = true }()
+
+	dir := t.TempDir()
+	laptop, workstation := newTestDB(t), newTestDB(t)
+	defer lockDB(laptop)
+	defer lockDB(workstation)
+
+	addChecklist(laptop, "Incident 42")
+	rekeyDB(laptop, "correct horse")
+	if _, _, err := syncDir(laptop, dir); err != nil {
+		t.Fatalf("syncDir(laptop) error = %v", err)
+	}
+
+	// Encrypting on its own, with the same passphrase, gives another key.
+	addChecklist(workstation, "Postmortem")
+	rekeyDB(workstation, "correct horse")
+	tests := []struct {
+		passphrase string
+		decrypt    bool
+		expected   string
+	}{
+		{"correct horse", false, "use another key"},
+		{"wrong", true, errWrongPassphrase.Error()},
+		{"correct horse", false, ""},
+	}
+	for index, test := range tests {
+		t.Setenv("CHKMRK_PASSPHRASE", test.passphrase)
+		if test.decrypt {
+			rekeyDB(workstation, "")
+		}
+		_, _, err := syncDir(workstation, dir)
+		if test.expected == "" && err != nil || test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)) {
+			t.Errorf("Test number %d -> syncDir(workstation) error = %v; expected %q", index, err, test.expected)
+		}
+	}
+	if _, _, err := syncDir(laptop, dir); err != nil {
+		t.Fatalf("syncDir(laptop) error = %v", err)
+	}
+
+	expected := []string{"# Incident 42", "# Postmortem"}
+	for index, db := range []*sql.DB{laptop, workstation} {
+		actual := syncState(t, db)
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Test number %d -> state after sync = %v; expected %v", index, actual, expected)
+		}
+	}
+	laptopKey, _ := getKey(laptop)
+	workstationKey, _ := getKey(workstation)
+	if !laptopKey.same(workstationKey) {
+		t.Errorf("the machines ended with different keys")
+	}
+
+	// What the folder holds is sealed.
+	data, _ := os.ReadFile(filepath.Join(dir, "key.json"))
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	for _, path := range files {
+		ops, _ := os.ReadFile(path)
+		data = append(data, ops...)
+	}
+	if strings.Contains(string(data), "Incident") || strings.Contains(string(data), "Postmortem") {
+		t.Errorf("the sync folder holds titles in plain text:\n%s", data)
+	}
+}
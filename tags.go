@@ -0,0 +1,85 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var retagCmd = &cobra.Command{
+	Use:   "retag <item-id>... <tags>",
+	Short: "Replace the tags of items",
+	Long: `Give every item the tags in the last argument, separated by spaces or
+commas, in place of the tags it had. An empty last argument removes them.
+In the TUI, t retags the items under the cursor or in the selection.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runRetag,
+}
+
+// normalizeTags turns what was typed for tags into their stored form:
+// distinct words separated by single spaces, without a leading #.
+func normalizeTags(tags string) string {
+	seen := map[string]bool{}
+	var words []string
+	for _, word := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		word = strings.TrimLeft(word, "#")
+		if word != "" && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// tagLabel is how tags read after an item's title.
+func tagLabel(tags string) string {
+	if tags == "" {
+		return ""
+	}
+	return "#" + strings.ReplaceAll(tags, " ", " #")
+}
+
+// retagItems replaces the tags of items in one transaction.
+func retagItems(db *sql.DB, ids []int, tags string) error {
+	tags, err := seal(db, normalizeTags(tags))
+	if err != nil {
+		return err
+	}
+	query := `UPDATE items SET tags = ? WHERE id = ? AND deleted_at IS NULL`
+	return execEach(db, query, ids, tags)
+}
+
+func SetTagsHandler(m *model) {
+	if err := retagItems(m.db, m.markedIDs(), m.textInput.Value()); err != nil {
+		m.err = err
+	}
+	m.editingTags = false
+	m.visual = false
+	m.reloadItems()
+}
+
+func runRetag(cmd *cobra.Command, args []string) error {
+	ids, err := parseIDs(args[:len(args)-1])
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	tags := normalizeTags(args[len(args)-1])
+	if err := retagItems(db, ids, tags); err != nil {
+		return err
+	}
+	if tags == "" {
+		fmt.Printf("Removed the tags of %s\n", itemCount(len(ids)))
+		return nil
+	}
+	fmt.Printf("Tagged %s %s\n", itemCount(len(ids)), tagLabel(tags))
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags     string
+		expected string
+		label    string
+	}{
+		{"", "", ""},
+		{"  ", "", ""},
+		{"release", "release", "#release"},
+		{"#release, urgent  release", "release urgent", "#release #urgent"},
+		{"a,b,,c", "a b c", "#a #b #c"},
+	}
+	for index, test := range tests {
+		actual := normalizeTags(test.tags)
+		if actual != test.expected || tagLabel(actual) != test.label {
+			t.Errorf("Test number %d -> normalizeTags(%q) = %q, labelled %q; expected %q, %q", index, test.tags, actual, tagLabel(actual), test.expected, test.label)
+		}
+	}
+}
+
+// itemTags lists the tags of checklist 1's items in order.
+func itemTags(t *testing.T, m model) []string {
+	t.Helper()
+	items, err := getItemsByChecklistId(m.db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := make([]string, len(items))
+	for i, item := range items {
+		tags[i] = item.Tags
+	}
+	return tags
+}
+
+func TestRetag(t *testing.T) {
+	tests := []struct {
+		keys     []string
+		expected []string
+	}{
+		{[]string{"t", "release", "enter"}, []string{"", "", "release"}},
+		{[]string{"V", "k", "t", "#ci, release", "enter"}, []string{"", "ci release", "ci release"}},
+		{[]string{"V", "k", "t", "ci", "esc"}, []string{"", "", ""}},
+	}
+
+	for index, test := range tests {
+		db := newTestDB(t)
+		addChecklist(db, "Release")
+		for _, title := range []string{"Tag", "Test", "Push"} {
+			addItem(db, title, false, 1)
+		}
+
+		m := initialModel(db, defaultKeyMap())
+		m.openChecklist()
+		m.moveCursor(2)
+		for _, k := range test.keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			switch k {
+			case "esc":
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			// The text input handlers return *model.
+			switch next, _ := m.Update(msg); next := next.(type) {
+			case model:
+				m = next
+			case *model:
+				m = *next
+			}
+		}
+
+		if actual := itemTags(t, m); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test number %d -> after %v, tags = %q; expected %q", index, test.keys, actual, test.expected)
+		}
+	}
+}
+
+func TestRetagCommand(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("CHKMRK_CONFIG", filepath.Join(root, "config.json"))
+	chdir(t, root)
+
+	db, closeDB, err := openDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+	addChecklist(db, "Release")
+	for _, title := range []string{"Tag", "Test", "Push"} {
+		addItem(db, title, false, 1)
+	}
+	m := model{db: db}
+
+	tests := []struct {
+		args     []string
+		fails    bool
+		expected []string
+	}{
+		{[]string{"1", "3", "release,ci"}, false, []string{"release ci", "", "release ci"}},
+		// One ID that isn't there leaves every item as it was.
+		{[]string{"2", "99", "docs"}, true, []string{"release ci", "", "release ci"}},
+		{[]string{"3", ""}, false, []string{"release ci", "", ""}},
+	}
+	for index, test := range tests {
+		if err := retagCmd.RunE(retagCmd, test.args); (err != nil) != test.fails {
+			t.Errorf("Test number %d -> retag %v error = %v; expected failure %v", index, test.args, err, test.fails)
+		}
+		if actual := itemTags(t, m); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test number %d -> after retag %v, tags = %q; expected %q", index, test.args, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+	"strings"
+)
+
+//go:embed templates/demo/*.md
+var demoTemplates embed.FS
+
+// checklistTemplate is a checklist read from a markdown file: a "# Title"
+// heading followed by "- [ ]" task lines.
+type checklistTemplate struct {
+	Title string
+	Lines []editLine
+}
+
+func parseTemplate(text string) checklistTemplate {
+	var t checklistTemplate
+	for _, line := range strings.Split(text, "\n") {
+		if title, ok := strings.CutPrefix(line, "# "); ok && t.Title == "" {
+			t.Title = strings.TrimSpace(title)
+		}
+	}
+	t.Lines = parseChecklist(text)
+	return t
+}
+
+// readTemplates parses every template in dir of fsys, in file name order.
+func readTemplates(fsys fs.FS, dir string) ([]checklistTemplate, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []checklistTemplate
+	for _, entry := range entries {
+		text, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		t := parseTemplate(string(text))
+		if t.Title == "" {
+			t.Title = strings.TrimSuffix(entry.Name(), ".md")
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// addChecklistFromTemplate creates a checklist and its items in one
+// transaction and returns the new checklist's ID.
+func addChecklistFromTemplate(db *sql.DB, t checklistTemplate) (int, error) {
+	var id int64
+	err := withTx(db, func(tx *sql.Tx) error {
+		title, err := seal(db, t.Title)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`INSERT INTO checklists (title) VALUES (?);`, title)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO items (title, completed, checklist_id, position, note) VALUES (?, ?, ?, ?, ?)`
+		for i, line := range t.Lines {
+			title, err := seal(db, line.Title)
+			if err != nil {
+				return err
+			}
+			note, err := seal(db, line.Note)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(query, title, line.Completed, id, i+1, note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return int(id), err
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestDemoTemplates(t *testing.T) {
+	templates, err := readTemplates(demoTemplates, "templates/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title string
+		items int
+	}{
+		{"My First Checklist", 28},
+		{"Release", 5},
+	}
+
+	if len(templates) != len(tests) {
+		t.Fatalf("readTemplates() returned %d templates; expected %d", len(templates), len(tests))
+	}
+
+	db := newTestDB(t)
+	for index, test := range tests {
+		if templates[index].Title != test.title || len(templates[index].Lines) != test.items {
+			t.Errorf("Test number %d -> template %q with %d items; expected %q with %d", index, templates[index].Title, len(templates[index].Lines), test.title, test.items)
+		}
+
+		id, err := addChecklistFromTemplate(db, templates[index])
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, _ := getItemsByChecklistId(db, id)
+		if len(items) != test.items {
+			t.Errorf("Test number %d -> checklist %d has %d items; expected %d", index, id, len(items), test.items)
+		}
+	}
+}
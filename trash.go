@@ -0,0 +1,418 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// defaultTrashDays is how long deleted things stay in the trash when the
+// config doesn't say.
+const defaultTrashDays = 30
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore and purge deleted checklists and items",
+	Long: `Deleted checklists and items go to the trash, newest first, and are
+purged once they are older than "trash_days" in the config (default 30).
+The restore and purge subcommands take numbers from trash list.`,
+	Args: cobra.NoArgs,
+	RunE: runTrashList,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the trash, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runTrashList,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <n>...",
+	Short: "Put checklists and items back where they were",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeTrash(args, restoreTrashEntry, "Restored", "")
+	},
+}
+
+var trashPurgeCmd = &cobra.Command{
+	Use:   "purge <n>...",
+	Short: "Delete checklists and items in the trash for good",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeTrash(args, purgeTrashEntry, "Purged", "Delete these for good?")
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Delete everything in the trash for good",
+	Args:  cobra.NoArgs,
+	RunE:  runTrashEmpty,
+}
+
+func init() {
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashPurgeCmd, trashEmptyCmd)
+}
+
+// trashEntry is a deleted checklist, or a deleted item of a checklist that
+// isn't. Items of a deleted checklist go and come back with it.
+type trashEntry struct {
+	Kind      string
+	ID        int
+	Title     string
+	Checklist string
+	DeletedAt time.Time
+}
+
+func (e trashEntry) String() string {
+	if e.Kind == "checklist" {
+		return fmt.Sprintf("[list] %s", e.Title)
+	}
+	return fmt.Sprintf("[item] %s (%s)", e.Title, e.Checklist)
+}
+
+// deleteQuestion asks to delete what: to the trash, or for good in a
+// markdown workspace, which has none.
+func deleteQuestion(db *sql.DB, what string) string {
+	if isMarkdown(db) {
+		return fmt.Sprintf("Delete %s for good? Markdown checklists have no trash.", what)
+	}
+	return fmt.Sprintf("Move %s to the trash?", what)
+}
+
+// deletedMessage says where what went once deleted.
+func deletedMessage(db *sql.DB, what string) string {
+	if isMarkdown(db) {
+		return fmt.Sprintf("Deleted %s for good", what)
+	}
+	return fmt.Sprintf("Moved %s to the trash", what)
+}
+
+// errNoTrash is returned for the trash of a markdown workspace.
+var errNoTrash = errors.New("markdown checklists have no trash; deleting is for good")
+
+// trashRetention is how long cfg keeps things in the trash.
+func trashRetention(cfg Config) time.Duration {
+	days := cfg.TrashDays
+	if days <= 0 {
+		days = defaultTrashDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// deleteChecklist moves a checklist and its items to the trash.
+func deleteChecklist(db *sql.DB, id int) error {
+	query := `UPDATE checklists SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err := db.Exec(query, time.Now().Unix(), id)
+	return err
+}
+
+// getTrash returns what's in the trash, newest first.
+func getTrash(db *sql.DB) ([]trashEntry, error) {
+	query := `
+	SELECT 'checklist', id, title, '', deleted_at FROM checklists WHERE deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'item', i.id, i.title, c.title, i.deleted_at
+	FROM items i JOIN checklists c ON c.id = i.checklist_id
+	WHERE i.deleted_at IS NOT NULL AND c.deleted_at IS NULL
+	ORDER BY 5 DESC, 2 DESC`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []trashEntry
+	for rows.Next() {
+		var e trashEntry
+		var deletedAt int64
+		if err := rows.Scan(&e.Kind, &e.ID, &e.Title, &e.Checklist, &deletedAt); err != nil {
+			return nil, err
+		}
+		if e.Title, err = unseal(db, e.Title); err != nil {
+			return nil, err
+		}
+		if e.Checklist, err = unseal(db, e.Checklist); err != nil {
+			return nil, err
+		}
+		e.DeletedAt = time.Unix(deletedAt, 0)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func restoreTrashEntry(db *sql.DB, e trashEntry) error {
+	query := `UPDATE items SET deleted_at = NULL WHERE id = ?`
+	if e.Kind == "checklist" {
+		query = `UPDATE checklists SET deleted_at = NULL WHERE id = ?`
+	}
+	_, err := db.Exec(query, e.ID)
+	return err
+}
+
+// purgeTrashEntry deletes a trashed checklist, with all its items, or a
+// trashed item for good.
+func purgeTrashEntry(db *sql.DB, e trashEntry) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		if e.Kind == "item" {
+			_, err := tx.Exec(`DELETE FROM items WHERE id = ? AND deleted_at IS NOT NULL`, e.ID)
+			return err
+		}
+		query := `DELETE FROM items WHERE checklist_id = (SELECT id FROM checklists WHERE id = ? AND deleted_at IS NOT NULL)`
+		if _, err := tx.Exec(query, e.ID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM checklists WHERE id = ? AND deleted_at IS NOT NULL`, e.ID)
+		return err
+	})
+}
+
+// purgeTrash deletes everything trashed at or before cutoff for good and
+// returns how many checklists and items went.
+func purgeTrash(db *sql.DB, cutoff time.Time) (int64, error) {
+	var purged int64
+	err := withTx(db, func(tx *sql.Tx) error {
+		purged = 0
+		queries := []string{
+			`DELETE FROM items WHERE deleted_at <= ?1 OR checklist_id IN (SELECT id FROM checklists WHERE deleted_at <= ?1)`,
+			`DELETE FROM checklists WHERE deleted_at <= ?1`,
+		}
+		for _, query := range queries {
+			result, err := tx.Exec(query, cutoff.Unix())
+			if err != nil {
+				return err
+			}
+			n, _ := result.RowsAffected()
+			purged += n
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// trashAge says how long ago something went to the trash.
+func trashAge(deletedAt, now time.Time) string {
+	age := now.Sub(deletedAt)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age/time.Minute))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age/time.Hour))
+	}
+	return fmt.Sprintf("%dd ago", int(age/(24*time.Hour)))
+}
+
+// openTrash shows the trash in place of the checklists.
+func (m *model) openTrash() {
+	if isMarkdown(m.db) {
+		m.err = errNoTrash
+		return
+	}
+	m.listCursor = m.cursor
+	m.layout = Trash
+	m.offset = 0
+	m.loadTrash()
+	m.setCursor(0)
+}
+
+func (m *model) loadTrash() {
+	m.trash, _ = getTrash(m.db)
+}
+
+// changeTrash restores or purges e and reloads the trash.
+func (m *model) changeTrash(e trashEntry, change func(db *sql.DB, e trashEntry) error) {
+	if err := change(m.db, e); err != nil {
+		m.err = err
+	}
+	m.loadTrash()
+	m.moveCursor(0)
+}
+
+func TrashAction(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.keys.Up):
+			m.moveCursor(-1)
+
+		case key.Matches(msg, m.keys.Down):
+			m.moveCursor(1)
+
+		case key.Matches(msg, m.keys.PageUp):
+			m.moveCursor(-m.listHeight())
+
+		case key.Matches(msg, m.keys.PageDown):
+			m.moveCursor(m.listHeight())
+
+		case key.Matches(msg, m.keys.Top):
+			m.moveCursor(-m.listLen())
+
+		case key.Matches(msg, m.keys.Bottom):
+			m.moveCursor(m.listLen())
+
+		case key.Matches(msg, m.keys.Restore):
+			if e, ok := m.cursorTrash(); ok {
+				m.changeTrash(e, restoreTrashEntry)
+			}
+
+		case key.Matches(msg, m.keys.Delete):
+			if e, ok := m.cursorTrash(); ok {
+				m.openConfirm("Delete this for good?", []string{e.String()}, func(m *model) tea.Cmd {
+					m.changeTrash(e, purgeTrashEntry)
+					return nil
+				})
+			}
+
+		case key.Matches(msg, m.keys.Back), key.Matches(msg, m.keys.Trash), msg.Type == tea.KeyEsc:
+			m.closeChecklist()
+
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = !m.showHelp
+
+		}
+	}
+
+	return m, nil
+}
+
+func TrashView(m model) string {
+	s := "\n  Trash" + workspaceLabel(m) + "\n\n"
+
+	now := time.Now()
+	start, end := visibleRange(m.cursor, m.offset, m.listHeight(), len(m.trash))
+	for i := start; i < end; i++ {
+		cursor := " "
+		if m.cursor == i {
+			cursor = ">"
+		}
+		s += fmt.Sprintf("%s %s  %s\n", cursor, m.trash[i], trashAge(m.trash[i].DeletedAt, now))
+	}
+	if len(m.trash) == 0 {
+		s += "  The trash is empty.\n"
+	}
+	s += "\n  " + pageIndicator(start, end, len(m.trash)) + "\n"
+	s += fmt.Sprintf("  %s restores, %s deletes for good. Purged after %d days.\n",
+		m.keys.Restore.Help().Key, m.keys.Delete.Help().Key, int(trashRetention(m.cfg)/(24*time.Hour)))
+
+	s += errorLine(m)
+	s += "\n" + m.help.View(m.keys) + "\n"
+
+	return s
+}
+
+// resolveTrash picks the entries numbered in args, counting from 1 as trash
+// list does.
+func resolveTrash(entries []trashEntry, args []string) ([]trashEntry, error) {
+	var picked []trashEntry
+	for _, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(entries) {
+			return nil, fmt.Errorf("no entry %q in the trash; see chkmrk trash list", arg)
+		}
+		picked = append(picked, entries[n-1])
+	}
+	return picked, nil
+}
+
+func runTrashList(cmd *cobra.Command, args []string) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if isMarkdown(db) {
+		return errNoTrash
+	}
+	entries, err := getTrash(db)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("The trash is empty")
+	}
+	now := time.Now()
+	for i, e := range entries {
+		fmt.Printf("%3d  %s  %s\n", i+1, e, trashAge(e.DeletedAt, now))
+	}
+	return nil
+}
+
+// changeTrash restores or purges the entries numbered in args, asking
+// question first unless it is empty.
+func changeTrash(args []string, change func(db *sql.DB, e trashEntry) error, verb, question string) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	entries, err := getTrash(db)
+	if err != nil {
+		return err
+	}
+	picked, err := resolveTrash(entries, args)
+	if err != nil {
+		return err
+	}
+	if question != "" {
+		preview := make([]string, len(picked))
+		for i, e := range picked {
+			preview[i] = e.String()
+		}
+		if err := confirmCLI(question, preview); err != nil {
+			return err
+		}
+	}
+	for _, e := range picked {
+		if err := change(db, e); err != nil {
+			return err
+		}
+		fmt.Printf("%s %s\n", verb, e)
+	}
+	return nil
+}
+
+func runTrashEmpty(cmd *cobra.Command, args []string) error {
+	db, closeDB, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	entries, err := getTrash(db)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("The trash is empty")
+		return nil
+	}
+	preview := make([]string, len(entries))
+	for i, e := range entries {
+		preview[i] = e.String()
+	}
+	if err := confirmCLI(fmt.Sprintf("Delete all %d entries in the trash for good?", len(entries)), preview); err != nil {
+		return err
+	}
+
+	n, err := purgeTrash(db, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d checklists and items\n", n)
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTrash(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addChecklist(db, "Groceries")
+	addItem(db, "Tag", false, 1)
+	addItem(db, "Push", false, 1)
+	addItem(db, "Milk", false, 2)
+
+	deleteItem(db, 1)
+	deleteChecklist(db, 2)
+	db.Exec(`UPDATE checklists SET deleted_at = deleted_at + 1 WHERE id = 2`)
+
+	lists, _ := getChecklists(db)
+	items, _ := getItemsByChecklistId(db, 1)
+	if len(lists) != 1 || len(items) != 1 || items[0].Title != "Push" {
+		t.Fatalf("after deleting, lists = %v and items = %v; expected only Release and Push", lists, items)
+	}
+	// Tag is in the trash, Milk in a checklist that is.
+	for _, id := range []int{1, 3} {
+		if _, err := getItemById(db, id); err == nil {
+			t.Errorf("getItemById(%d) found a trashed item", id)
+		}
+	}
+
+	tests := []trashEntry{
+		{Kind: "checklist", ID: 2, Title: "Groceries"},
+		{Kind: "item", ID: 1, Title: "Tag", Checklist: "Release"},
+	}
+	trash, err := getTrash(db)
+	if err != nil || len(trash) != len(tests) {
+		t.Fatalf("getTrash() = %v, %v; expected %d entries", trash, err, len(tests))
+	}
+	for index, test := range tests {
+		actual := trash[index]
+		actual.DeletedAt = time.Time{}
+		if actual != test {
+			t.Errorf("Test number %d -> getTrash() entry = %+v; expected %+v", index, actual, test)
+		}
+	}
+
+	restoreTrashEntry(db, trash[1])
+	if items, _ := getItemsByChecklistId(db, 1); len(items) != 2 || items[0].Title != "Tag" {
+		t.Errorf("after restore, items = %v; expected Tag back in its place", items)
+	}
+
+	purgeTrashEntry(db, trash[0])
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM items WHERE checklist_id = 2`).Scan(&count)
+	if trash, _ := getTrash(db); len(trash) != 0 || count != 0 {
+		t.Errorf("after purge, trash = %v with %d items left; expected nothing", trash, count)
+	}
+
+	// Only what was trashed before the cutoff is purged.
+	deleteItem(db, 1)
+	deleteItem(db, 2)
+	db.Exec(`UPDATE items SET deleted_at = deleted_at - 40 * 86400 WHERE id = 1`)
+	if n, err := purgeTrash(db, time.Now().Add(-trashRetention(Config{}))); n != 1 || err != nil {
+		t.Errorf("purgeTrash() = %d, %v; expected 1 purged", n, err)
+	}
+	if trash, _ := getTrash(db); len(trash) != 1 || trash[0].ID != 2 {
+		t.Errorf("after purgeTrash(), trash = %v; expected only Push", trash)
+	}
+}
+
+func TestTrashLayout(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addChecklist(db, "Groceries")
+
+	keys := defaultKeyMap()
+	press := func(m tea.Model, k string) model {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		return next.(model)
+	}
+
+	m := initialModel(db, keys)
+	m = press(press(m, "x"), "y")
+	if len(m.checklists) != 1 || m.checklists[0].Title != "Groceries" {
+		t.Fatalf("after delete, checklists = %v; expected Groceries", m.checklists)
+	}
+
+	m = press(m, "T")
+	if m.layout != Trash || len(m.trash) != 1 {
+		t.Fatalf("after T, layout = %d with trash %v; expected the trash with Release", m.layout, m.trash)
+	}
+
+	m = press(m, "u")
+	if len(m.trash) != 0 {
+		t.Errorf("after restore, trash = %v; expected it empty", m.trash)
+	}
+	m = press(m, "h")
+	if m.layout != Checklists || len(m.checklists) != 2 {
+		t.Errorf("after back, layout = %d with %v; expected both checklists", m.layout, m.checklists)
+	}
+}
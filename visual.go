@@ -0,0 +1,62 @@
+package main
+
+// markedRange returns the half-open range of rows the next action applies
+// to: the visual selection when one is active, otherwise the cursor row.
+// Toggle, delete, move, copy and retag act on it.
+func (m model) markedRange() (int, int) {
+	if !m.visual {
+		return m.cursor, m.cursor + 1
+	}
+	return min(m.anchor, m.cursor), max(m.anchor, m.cursor) + 1
+}
+
+func (m model) isMarked(i int) bool {
+	start, end := m.markedRange()
+	return m.visual && i >= start && i < end
+}
+
+func (m model) markedItems() []Item {
+	start, end := m.markedRange()
+	end = min(end, len(m.items))
+	if start >= end {
+		return nil
+	}
+	return m.items[start:end]
+}
+
+func (m model) markedIDs() []int {
+	var ids []int
+	for _, item := range m.markedItems() {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+// markedTitles lists the titles of the marked rows, to preview what a bulk
+// action will touch.
+func (m model) markedTitles() []string {
+	var titles []string
+	for _, item := range m.markedItems() {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+// allMarkedCompleted reports whether every marked row is checked, in which
+// case toggling unchecks them all; otherwise toggling checks them all.
+func (m model) allMarkedCompleted() bool {
+	start, end := m.markedRange()
+	for i := start; i < end; i++ {
+		if _, ok := m.selected[i]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *model) startVisual() {
+	if !m.visual {
+		m.visual = true
+		m.anchor = m.cursor
+	}
+}
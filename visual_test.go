@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarkedIDs(t *testing.T) {
+	items := []Item{{ID: 10}, {ID: 11}, {ID: 12}, {ID: 13}}
+
+	tests := []struct {
+		visual   bool
+		anchor   int
+		cursor   int
+		expected []int
+	}{
+		{false, 0, 2, []int{12}},
+		{true, 1, 3, []int{11, 12, 13}},
+		{true, 3, 1, []int{11, 12, 13}},
+		{true, 2, 2, []int{12}},
+	}
+
+	for index, test := range tests {
+		m := model{items: items, visual: test.visual, anchor: test.anchor, cursor: test.cursor}
+		actual := m.markedIDs()
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test number %d -> markedIDs() = %v; expected %v", index, actual, test.expected)
+		}
+	}
+}
+
+func TestAllMarkedCompleted(t *testing.T) {
+	tests := []struct {
+		selected map[int]Item
+		expected bool
+	}{
+		{map[int]Item{1: {}, 2: {}, 3: {}}, true},
+		{map[int]Item{1: {}, 3: {}}, false},
+		{map[int]Item{}, false},
+	}
+
+	for index, test := range tests {
+		m := model{visual: true, anchor: 1, cursor: 3, selected: test.selected}
+		actual := m.allMarkedCompleted()
+
+		if actual != test.expected {
+			t.Errorf("Test number %d -> allMarkedCompleted() = %v; expected %v", index, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// watchInterval is how often the TUI checks the database for changes made
+// by other processes.
+const watchInterval = time.Second
+
+type watchTickMsg struct{}
+
+// changeWatcher notices writes to a database file by polling SQLite's
+// data_version on a connection of its own. data_version only changes when
+// another connection commits, and to this connection every other writer,
+// in this process or another, is another connection.
+// A database served by a daemon is watched through the daemon instead.
+type changeWatcher struct {
+	conn    *sql.DB
+	version int64
+	daemon  *daemonWatcher
+}
+
+// newChangeWatcher watches the file behind db. In-memory databases can't
+// be changed from outside, so for those it returns nil.
+func newChangeWatcher(db *sql.DB) (*changeWatcher, error) {
+	if d, ok := db.Driver().(daemonDriver); ok {
+		w, err := newDaemonWatcher(d.socket)
+		if err != nil {
+			return nil, err
+		}
+		return &changeWatcher{daemon: w}, nil
+	}
+
+	file, err := dbFile(db)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return nil, nil
+	}
+
+	conn, err := sql.Open("sqlite3", sqliteDSN(file))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetMaxOpenConns(1)
+
+	w := &changeWatcher{conn: conn}
+	if _, err := w.changed(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// changed reports whether the database was written since the last call.
+func (w *changeWatcher) changed() (bool, error) {
+	if w.daemon != nil {
+		return w.daemon.take(), nil
+	}
+
+	var version int64
+	if err := w.conn.QueryRow(`PRAGMA data_version;`).Scan(&version); err != nil {
+		return false, err
+	}
+	changed := version != w.version
+	w.version = version
+	return changed, nil
+}
+
+func (w *changeWatcher) Close() error {
+	if w == nil {
+		return nil
+	}
+	if w.daemon != nil {
+		return w.daemon.client.Close()
+	}
+	return w.conn.Close()
+}
+
+func watchTick() tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		return watchTickMsg{}
+	})
+}
+
+// WatchTickAction reloads the checklists and the open checklist when the
+// database changed, then schedules the next check.
+func WatchTickAction(m model) (tea.Model, tea.Cmd) {
+	if m.watcher != nil {
+		if changed, err := m.watcher.changed(); err == nil && changed {
+			m.refresh()
+		}
+	}
+	return m, watchTick()
+}
+
+// refresh reads everything on screen back from the database, keeping the
+// cursor on the same checklist or item when it still exists.
+func (m *model) refresh() {
+	lists, err := getChecklists(m.db)
+	if err != nil {
+		return
+	}
+
+	if m.layout == Checklists {
+		m.cursor = indexOfChecklist(lists, m.checklists, m.cursor)
+	} else {
+		m.listCursor = indexOfChecklist(lists, m.checklists, m.listCursor)
+	}
+	m.checklists = lists
+
+	if m.activeList != -1 {
+		var cursorID, anchorID int
+		if item, ok := m.cursorItem(); ok {
+			cursorID = item.ID
+		}
+		if m.anchor < len(m.items) {
+			anchorID = m.items[m.anchor].ID
+		}
+		m.reloadItems()
+		m.cursor = indexOfItem(m.items, cursorID, m.cursor)
+		m.anchor = min(indexOfItem(m.items, anchorID, m.anchor), max(len(m.items)-1, 0))
+	}
+	if m.layout == Trash {
+		m.loadTrash()
+	}
+
+	m.moveCursor(0)
+	m.loadPreview()
+}
+
+// indexOfChecklist finds the checklist at index i of old in lists, or
+// returns i if it's gone.
+func indexOfChecklist(lists, old []Checklist, i int) int {
+	if i < len(old) {
+		for j, list := range lists {
+			if list.ID == old[i].ID {
+				return j
+			}
+		}
+	}
+	return i
+}
+
+// indexOfItem finds the item with id in items, or returns fallback if it's
+// gone.
+func indexOfItem(items []Item, id, fallback int) int {
+	for i, item := range items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestChangeWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checklist.db")
+	db, closeDB, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB()
+	addChecklist(db, "Release")
+	addItem(db, "Tag", false, 1)
+	addItem(db, "Test", false, 1)
+
+	w, err := newChangeWatcher(db)
+	if err != nil || w == nil {
+		t.Fatalf("newChangeWatcher() = %v, %v; expected a watcher", w, err)
+	}
+	defer w.Close()
+
+	m := initialModel(db, defaultKeyMap())
+	m.openChecklist()
+	m.moveCursor(1)
+
+	if changed, _ := w.changed(); changed {
+		t.Errorf("changed() = true before any write")
+	}
+
+	// Another process puts a new item first and checks off the cursor item.
+	other, closeOther, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addItem(other, "Bump version", false, 1)
+	other.Exec(`UPDATE items SET position = 0 WHERE title = 'Bump version'`)
+	updateItemCompleted(other, 2, true)
+	closeOther()
+
+	if changed, _ := w.changed(); !changed {
+		t.Fatalf("changed() = false after another connection wrote")
+	}
+	m.refresh()
+
+	if len(m.items) != 3 || m.cursor != 2 || m.items[m.cursor].ID != 2 {
+		t.Errorf("after refresh cursor = %d on %v; expected 2 on item 2", m.cursor, m.items)
+	}
+	if !m.items[m.cursor].Completed {
+		t.Errorf("item 2 not completed after refresh")
+	}
+
+	memory, err := newChangeWatcher(newTestDB(t))
+	if err != nil || memory != nil {
+		t.Errorf("newChangeWatcher(:memory:) = %v, %v; expected nil", memory, err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed web/*.html
+var webFiles embed.FS
+
+// htmxJS is htmx 1.9.12, served from the binary rather than a CDN.
+//
+//go:embed web/htmx.min.js
+var htmxJS []byte
+
+// webToken goes in every toggle form this process serves and must come
+// back with the post, so a form on another site can't toggle items.
+var webToken = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+var webTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"token": func() string { return webToken },
+}).ParseFS(webFiles, "web/*.html"))
+
+var webAddrFlag string
+
+var webCmd = &cobra.Command{
+	Use:   "web",
+	Short: "Serve the workspace as a web page",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serveWorkspace(webAddrFlag, newWebUI)
+	},
+}
+
+func init() {
+	webCmd.Flags().StringVar(&webAddrFlag, "addr", "127.0.0.1:8080", "address to listen on")
+}
+
+// webUI serves an HTML page of the checklists where items can be checked
+// off. With htmx loaded a toggle swaps just the item's row; without it the
+// toggle is a plain form post that redirects back. The JSON API is mounted
+// under /api.
+type webUI struct {
+	api
+}
+
+func newWebUI(db *sql.DB) http.Handler {
+	w := webUI{api{db: db}}
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", newAPI(db)))
+	mux.HandleFunc("/", w.index)
+	mux.HandleFunc("/htmx.min.js", w.htmx)
+	mux.HandleFunc("/checklists/", w.checklist)
+	mux.HandleFunc("/items/", w.toggle)
+	return mux
+}
+
+type webPage struct {
+	Checklists []Checklist
+	Active     *apiChecklist
+}
+
+func (ui webUI) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := webTemplates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ui webUI) page(w http.ResponseWriter, active *apiChecklist) {
+	lists, err := getChecklists(ui.db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ui.render(w, "page", webPage{Checklists: lists, Active: active})
+}
+
+func (ui webUI) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	ui.page(w, nil)
+}
+
+func (ui webUI) htmx(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "max-age=86400")
+	w.Write(htmxJS)
+}
+
+func (ui webUI) checklist(w http.ResponseWriter, r *http.Request) {
+	id, rest, err := pathID(r.URL.Path, "/checklists/")
+	if err != nil || rest != "" {
+		http.NotFound(w, r)
+		return
+	}
+	list, err := ui.loadChecklist(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	ui.page(w, &list)
+}
+
+func (ui webUI) toggle(w http.ResponseWriter, r *http.Request) {
+	id, rest, err := pathID(r.URL.Path, "/items/")
+	if err != nil || rest != "toggle" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := checkOrigin(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.PostFormValue("token")), []byte(webToken)) != 1 {
+		http.Error(w, "stale or missing form token; reload the page", http.StatusForbidden)
+		return
+	}
+
+	item, err := getItemById(ui.db, id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := checkCompletable(ui.db, id, !item.Completed); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err := updateItemCompleted(ui.db, id, !item.Completed); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	item.Completed = !item.Completed
+
+	if r.Header.Get("HX-Request") == "" {
+		http.Redirect(w, r, fmt.Sprintf("/checklists/%d", item.ChecklistID), http.StatusSeeOther)
+		return
+	}
+	ui.render(w, "item", toAPIItem(item))
+}
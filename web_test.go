@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebUI(t *testing.T) {
+	db := newTestDB(t)
+	addChecklist(db, "Release")
+	addItem(db, "Tag <v2>", false, 1)
+	ui := newWebUI(db)
+
+	form := "token=" + webToken
+	tests := []struct {
+		method, path string
+		form         string
+		htmx         bool
+		status       int
+		contains     string
+	}{
+		{"GET", "/", "", false, http.StatusOK, `href="/checklists/1"`},
+		{"GET", "/checklists/1", "", false, http.StatusOK, `name="token" value="` + webToken + `"`},
+		{"GET", "/checklists/1", "", false, http.StatusOK, "[ ] Tag &lt;v2&gt;"},
+		{"GET", "/checklists/2", "", false, http.StatusNotFound, ""},
+		{"GET", "/htmx.min.js", "", false, http.StatusOK, `version:"1.9.12"`},
+		{"POST", "/items/1/toggle", form, true, http.StatusOK, `<li id="item-1" class="completed">`},
+		{"POST", "/items/1/toggle", form, false, http.StatusSeeOther, ""},
+		// A form on another site doesn't know the token.
+		{"POST", "/items/1/toggle", "", false, http.StatusForbidden, ""},
+		{"POST", "/items/1/toggle", "token=guess", true, http.StatusForbidden, ""},
+		{"GET", "/items/1/toggle", "", false, http.StatusMethodNotAllowed, ""},
+		{"GET", "/api/checklists", "", false, http.StatusOK, `"title":"Release"`},
+	}
+
+	for index, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		rec := httptest.NewRecorder()
+		ui.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("Test number %d -> %s %s = %d; expected %d", index, test.method, test.path, rec.Code, test.status)
+		}
+		if !strings.Contains(rec.Body.String(), test.contains) {
+			t.Errorf("Test number %d -> %s %s body = %q; expected it to contain %q", index, test.method, test.path, rec.Body.String(), test.contains)
+		}
+	}
+
+	item, _ := getItemById(db, 1)
+	if item.Completed {
+		t.Errorf("item completed after two toggles")
+	}
+}
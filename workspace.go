@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ChkMrk/cmd"
+)
+
+// defaultWorkspace is the name used when neither --workspace nor the config
+// picks one. It keeps using checklist.db in the working directory.
+const defaultWorkspace = "default"
+
+// resolveWorkspace returns the workspace name to open for the requested
+// name, falling back to the configured default.
+func resolveWorkspace(cfg Config, name string) string {
+	if name == "" {
+		name = cfg.DefaultWorkspace
+	}
+	if name == "" {
+		name = defaultWorkspace
+	}
+	return name
+}
+
+// workspacePath returns the database file for a workspace. Workspaces that
+// aren't listed in the config get a database in the config directory, so
+// their names can't reach outside it.
+func workspacePath(cfg Config, name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid workspace name %q: it can't contain path separators or \"..\"", name)
+	}
+	if path, ok := cfg.Workspaces[name]; ok {
+		return path, nil
+	}
+	if name == defaultWorkspace {
+		return "./checklist.db", nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "chkmrk", "workspaces")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".db"), nil
+}
+
+// workspaceNames lists the configured workspaces plus the default one.
+func workspaceNames(cfg Config) []string {
+	names := []string{defaultWorkspace}
+	for name := range cfg.Workspaces {
+		if name != defaultWorkspace {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// currentWorkspace resolves what chkmrk should open to a name and database
+// file: the workspace chosen with --workspace, else a project found above
+// the working directory, else the default workspace.
+func currentWorkspace() (string, string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", "", err
+	}
+	if marker, ok := discoverProject(); ok {
+		return projectName(marker), projectDBPath(marker), nil
+	}
+	name := resolveWorkspace(cfg, cmd.Workspace)
+	path, err := workspacePath(cfg, name)
+	return name, path, err
+}
+
+// discoverProject looks for a project above the working directory unless a
+// workspace was asked for explicitly.
+func discoverProject() (string, bool) {
+	if cmd.Workspace != "" {
+		return "", false
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	return findProject(cwd)
+}
+
+// openDB opens the current workspace as chosen by currentWorkspace. The
+// returned function must be called to close it.
+func openDB() (*sql.DB, func() error, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, closeDB, _, err := openCurrent(cfg)
+	return db, closeDB, err
+}
+
+func openCurrent(cfg Config) (*sql.DB, func() error, string, error) {
+	if marker, ok := discoverProject(); ok {
+		if db, ok := dialDaemon(projectDBPath(marker)); ok {
+			return db, closeSealed(db), projectName(marker), unlockDB(db)
+		}
+		db, closeDB, err := openProject(marker)
+		return db, closeDB, projectName(marker), err
+	}
+	return openWorkspace(cfg, cmd.Workspace)
+}
+
+func openWorkspace(cfg Config, name string) (*sql.DB, func() error, string, error) {
+	name = resolveWorkspace(cfg, name)
+	path, err := workspacePath(cfg, name)
+	if err != nil {
+		return nil, nil, name, err
+	}
+
+	if db, ok := dialDaemon(path); ok {
+		return db, closeSealed(db), name, unlockDB(db)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		db, closeDB, err := openMarkdownDir(path)
+		return db, closeDB, name, err
+	}
+	db, closeDB, err := openSQLite(path)
+	return db, closeDB, name, err
+}
+
+// sqliteDSN configures every connection to path for several processes
+// sharing the file: WAL so readers don't block the writer, a busy timeout
+// so writers wait for each other instead of failing with "database is
+// locked", enforced foreign keys, and transactions that take the write lock
+// when they begin, since a busy timeout can't help a read transaction that
+// later needs to write.
+func sqliteDSN(path string) string {
+	return "file:" + path + "?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on&_txlock=immediate"
+}
+
+func openSQLite(path string) (*sql.DB, func() error, error) {
+	db, err := sql.Open("sqlite3", sqliteDSN(path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, _ := loadConfig()
+	if err := autoBackup(db, path, cfg.Backups); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("backing up %s: %w", path, err)
+	}
+
+	// Initialize the database schema.
+	if err := initializeDB(db); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	if err := unlockDB(db); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	if _, err := purgeTrash(db, time.Now().Add(-trashRetention(cfg))); err != nil {
+		log.Printf("Purging the trash of %s failed: %s", path, err)
+	}
+
+	return db, closeSealed(db), nil
+}
+
+// workspaceLabel names the open workspace in titles, unless it's the default.
+func workspaceLabel(m model) string {
+	if m.workspace == "" || m.workspace == defaultWorkspace {
+		return ""
+	}
+	return " (" + m.workspace + ")"
+}
+
+// switchWorkspace closes the current database and opens the named one.
+func (m *model) switchWorkspace(name string) {
+	db, closeDB, name, err := openWorkspace(m.cfg, name)
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	if m.closeDB != nil {
+		m.closeDB()
+	}
+	m.watcher.Close()
+	m.db = db
+	m.closeDB = closeDB
+	m.watcher, _ = newChangeWatcher(db)
+	m.workspace = name
+	m.activeList = -1
+	m.checklists, _ = getChecklists(db)
+	m.layout = Checklists
+	m.offset = 0
+	m.listCursor = 0
+	m.setCursor(0)
+	m.visual = false
+	m.loadPreview()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWorkspacePath(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+
+	cfg := Config{
+		Workspaces:       map[string]string{"team": "/srv/team.db"},
+		DefaultWorkspace: "team",
+	}
+
+	tests := []struct {
+		cfg      Config
+		name     string
+		expected string
+	}{
+		{Config{}, "", "./checklist.db"},
+		{cfg, "", "/srv/team.db"},
+		{cfg, "team", "/srv/team.db"},
+		{cfg, "default", "./checklist.db"},
+		{cfg, "personal", filepath.Join(configDir, "chkmrk", "workspaces", "personal.db")},
+	}
+
+	for index, test := range tests {
+		actual, err := workspacePath(test.cfg, resolveWorkspace(test.cfg, test.name))
+		if err != nil {
+			t.Fatalf("Test number %d -> workspacePath() error = %v", index, err)
+		}
+		if actual != test.expected {
+			t.Errorf("Test number %d -> workspacePath(%q) = %q; expected %q", index, test.name, actual, test.expected)
+		}
+	}
+
+	for index, name := range []string{"../../etc/passwd", "team/../x", `..\x`, "a..b"} {
+		if path, err := workspacePath(cfg, name); err == nil {
+			t.Errorf("Test number %d -> workspacePath(%q) = %q; expected an error", index, name, path)
+		}
+	}
+
+	names := workspaceNames(Config{Workspaces: map[string]string{"work": "", "home": "", "default": ""}})
+	if !reflect.DeepEqual(names, []string{"default", "home", "work"}) {
+		t.Errorf("workspaceNames() = %v; expected %v", names, []string{"default", "home", "work"})
+	}
+}